@@ -0,0 +1,290 @@
+package jsontree
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Bundle holds a tree per locale and resolves key paths through fallback
+// chains. The chain of a locale is the locale itself, the locales obtained
+// by removing its last subtags, and the fallback locale: "de-AT", "de" and
+// "en" for "de-AT" with "en" as the fallback. Locales without a tree are
+// left out of chains.
+//
+// Paths are below the roots of the trees, as the keys of the roots are
+// usually the locales. The tree of the fallback locale is the source of the
+// other trees: its leaves are the keys that MissingKeys and Coverage look for.
+type Bundle struct {
+	fallback string
+	locales  []string
+	trees    map[string]Node
+}
+
+func NewBundle(fallback string) *Bundle {
+	return &Bundle{fallback: fallback, trees: make(map[string]Node)}
+}
+
+// Add sets the tree of locale. Locales are compared without regard to case,
+// and '_' is the same as '-'. A nil tree removes the locale.
+func (b *Bundle) Add(locale string, tree Node) {
+	id := localeID(locale)
+	if tree == nil {
+		if _, ok := b.trees[id]; ok {
+			delete(b.trees, id)
+			for i, l := range b.locales {
+				if localeID(l) == id {
+					b.locales = append(b.locales[:i:i], b.locales[i+1:]...)
+					break
+				}
+			}
+		}
+		return
+	}
+	if _, ok := b.trees[id]; !ok {
+		b.locales = append(b.locales, locale)
+	}
+	b.trees[id] = tree
+}
+
+// Locales returns the locales that have a tree, in the order they were added
+func (b *Bundle) Locales() []string {
+	return append([]string(nil), b.locales...)
+}
+
+// Tree returns the tree of locale, or nil
+func (b *Bundle) Tree(locale string) Node {
+	return b.trees[localeID(locale)]
+}
+
+// Chain returns the fallback chain of locale
+func (b *Bundle) Chain(locale string) []string {
+	var chain []string
+	seen := make(map[string]bool)
+	add := func(locale string) {
+		id := localeID(locale)
+		if tree, ok := b.trees[id]; ok && tree != nil && !seen[id] {
+			seen[id] = true
+			chain = append(chain, b.name(id))
+		}
+	}
+	for id := localeID(locale); id != ""; {
+		add(id)
+		i := strings.LastIndexByte(id, '-')
+		if i < 0 {
+			break
+		}
+		id = id[:i]
+	}
+	add(b.fallback)
+	return chain
+}
+
+// Lookup returns the node at path in the first tree of the chain of locale
+// that has one, and the locale of that tree. It returns nil and "" if no tree
+// has the path.
+func (b *Bundle) Lookup(locale string, path ...[]byte) (Node, string) {
+	for _, l := range b.Chain(locale) {
+		tree := b.Tree(l)
+		if len(path) == 0 {
+			return tree, l
+		}
+		if n := getNode(tree, path...); n != nil {
+			return n, l
+		}
+	}
+	return nil, ""
+}
+
+// MissingKeys returns the paths of the leaves of the fallback tree that the
+// tree of locale does not have. Keys only found through the chain of locale
+// are missing.
+func (b *Bundle) MissingKeys(locale string) ([][][]byte, error) {
+	source := b.Tree(b.fallback)
+	if source == nil {
+		return nil, fmt.Errorf("no tree for the fallback locale \"%s\"", b.fallback)
+	}
+	paths, err := leafPaths(source)
+	if err != nil {
+		return nil, err
+	}
+	tree := b.Tree(locale)
+	var missing [][][]byte
+	for _, path := range paths {
+		if tree == nil || !isLeaf(getNode(tree, path...)) {
+			missing = append(missing, path)
+		}
+	}
+	return missing, nil
+}
+
+// Coverage is how many of the leaves of the fallback tree a locale has
+type Coverage struct {
+	Locale  string
+	Present int
+	Total   int
+}
+
+// Percent returns the percentage of the leaves present, 100 if the fallback
+// tree has none
+func (c Coverage) Percent() float64 {
+	if c.Total == 0 {
+		return 100
+	}
+	return 100 * float64(c.Present) / float64(c.Total)
+}
+
+// Coverage returns the coverage of every locale, in the order they were added
+func (b *Bundle) Coverage() ([]Coverage, error) {
+	source := b.Tree(b.fallback)
+	if source == nil {
+		return nil, fmt.Errorf("no tree for the fallback locale \"%s\"", b.fallback)
+	}
+	paths, err := leafPaths(source)
+	if err != nil {
+		return nil, err
+	}
+	coverage := make([]Coverage, len(b.locales))
+	for i, locale := range b.locales {
+		missing, err := b.MissingKeys(locale)
+		if err != nil {
+			return nil, err
+		}
+		coverage[i] = Coverage{Locale: locale, Present: len(paths) - len(missing), Total: len(paths)}
+	}
+	return coverage, nil
+}
+
+// View returns a Node merging the trees of the chain of locale, without
+// copying them. The children of a node are those of the nodes at its path in
+// every tree, in the order of the fallback tree first, and the value of a
+// leaf is the one of the first tree having it. AddNode adds the nodes to the
+// tree of locale itself, and returns nil if locale has no tree. SetKey only
+// changes the key of the view. View returns nil if the chain is empty.
+func (b *Bundle) View(locale string) Node {
+	chain := b.Chain(locale)
+	if len(chain) == 0 {
+		return nil
+	}
+	v := &viewNode{nodes: make([]Node, len(chain)), tree: b.Tree(locale)}
+	for i, l := range chain {
+		v.nodes[i] = b.Tree(l)
+	}
+	v.key = v.nodes[0].Key()
+	return v
+}
+
+// viewNode is a node of a Bundle view. nodes are the nodes at its path in
+// the trees of the chain, nil where a tree does not have the path.
+type viewNode struct {
+	key   []byte
+	nodes []Node
+	tree  Node // the tree AddNode adds to, if any
+	path  [][]byte
+}
+
+func (v *viewNode) Key() []byte {
+	return v.key
+}
+
+func (v *viewNode) SetKey(key []byte) {
+	v.key = key
+}
+
+func (v *viewNode) Value() Value {
+	for _, n := range v.nodes {
+		if isLeaf(n) {
+			return n.Value()
+		}
+	}
+	return nil
+}
+
+func (v *viewNode) Nodes() []Node {
+	var children []Node
+	for i := len(v.nodes) - 1; i >= 0; i-- {
+		if v.nodes[i] == nil {
+			continue
+		}
+		for _, child := range v.nodes[i].Nodes() {
+			if child == nil || v.hasChild(children, child.Key()) {
+				continue
+			}
+			children = append(children, v.child(child.Key()))
+		}
+	}
+	return children
+}
+
+func (v *viewNode) AddNode(key []byte) Node {
+	if v.tree == nil {
+		return nil
+	}
+	// The tree of the locale is the first of the chain
+	c := v.child(key)
+	c.nodes[0] = getOrAddNode(v.tree, c.path...)
+	if v.nodes[0] == nil {
+		v.nodes[0] = getNode(v.tree, v.path...)
+	}
+	return c
+}
+
+func (v *viewNode) hasChild(children []Node, key []byte) bool {
+	for _, child := range children {
+		if keyEqual(child.Key(), key) {
+			return true
+		}
+	}
+	return false
+}
+
+// child returns the view of the child of v with key
+func (v *viewNode) child(key []byte) *viewNode {
+	c := &viewNode{key: key, nodes: make([]Node, len(v.nodes)), tree: v.tree, path: append(v.path[:len(v.path):len(v.path)], key)}
+	for i, n := range v.nodes {
+		if n != nil {
+			c.nodes[i] = getNode(n, key)
+		}
+	}
+	return c
+}
+
+// name returns the locale of id as it was added
+func (b *Bundle) name(id string) string {
+	for _, locale := range b.locales {
+		if localeID(locale) == id {
+			return locale
+		}
+	}
+	return id
+}
+
+func localeID(locale string) string {
+	return strings.ToLower(strings.Replace(locale, "_", "-", -1))
+}
+
+func isLeaf(node Node) bool {
+	return node != nil && len(node.Nodes()) == 0 && node.Value() != nil
+}
+
+// leafPaths returns the paths of the leaves of node, below node
+func leafPaths(node Node) ([][][]byte, error) {
+	var paths [][][]byte
+	var walk func(node Node, path [][]byte) error
+	walk = func(node Node, path [][]byte) error {
+		for _, child := range node.Nodes() {
+			if child == nil {
+				return fmt.Errorf("invalid node: node.Nodes() contained nil")
+			}
+			path := append(path[:len(path):len(path)], child.Key())
+			if len(child.Nodes()) > 0 {
+				if err := walk(child, path); err != nil {
+					return err
+				}
+			} else {
+				paths = append(paths, path)
+			}
+		}
+		return nil
+	}
+	return paths, walk(node, nil)
+}
@@ -0,0 +1,129 @@
+package jsontree
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testBundle(t *testing.T) *Bundle {
+	b := NewBundle("en")
+	for _, doc := range []string{
+		`{"en":{"hello":"Hello","bye":"Goodbye","menu":{"open":"Open","close":"Close"}}}`,
+		`{"de":{"hello":"Hallo","menu":{"open":"Öffnen"},"extra":"x"}}`,
+		`{"de_AT":{"hello":"Servus"}}`,
+	} {
+		node := &testNode{}
+		if err := DeserializeNode(node, strings.NewReader(doc)); err != nil {
+			t.Fatalf("DeserializeNode() error: %v", err)
+		}
+		b.Add(string(node.key), node)
+	}
+	return b
+}
+
+func TestBundleLookup(t *testing.T) {
+	b := testBundle(t)
+	if got, want := b.Chain("de-at-x-private"), []string{"de_AT", "de", "en"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Wrong chain\nWant %q\nGot  %q", want, got)
+	}
+	if got, want := b.Chain("fr"), []string{"en"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Wrong chain\nWant %q\nGot  %q", want, got)
+	}
+	tests := []struct {
+		locale string
+		path   string
+		want   string
+		from   string
+	}{
+		{"de-AT", "hello", "Servus", "de_AT"},
+		{"de-AT", "menu.open", "Öffnen", "de"},
+		{"de-AT", "menu.close", "Close", "en"},
+		{"de-CH", "hello", "Hallo", "de"},
+		{"de-AT", "missing", "", ""},
+	}
+	for _, test := range tests {
+		n, from := b.Lookup(test.locale, bytes.Split([]byte(test.path), []byte{'.'})...)
+		if from != test.from {
+			t.Errorf("%s %s: Wrong locale %q, want %q", test.locale, test.path, from, test.from)
+		}
+		if n == nil {
+			if test.want != "" {
+				t.Errorf("%s %s: Lookup() returned nil", test.locale, test.path)
+			}
+			continue
+		}
+		if got, _ := n.Value().Serialize(); string(got) != test.want {
+			t.Errorf("%s %s: Wrong value %s, want %s", test.locale, test.path, got, test.want)
+		}
+	}
+	if n, from := b.Lookup("de"); from != "de" || n != b.Tree("DE") {
+		t.Errorf("Lookup() without path returned %v, %q", n, from)
+	}
+
+	// A nil tree removes the locale
+	b.Add("DE", nil)
+	b.Add("fr", nil)
+	if got, want := b.Chain("de-AT"), []string{"de_AT", "en"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Wrong chain\nWant %q\nGot  %q", want, got)
+	}
+	if n, from := b.Lookup("de-AT", key("menu"), key("open")); n == nil || from != "en" {
+		t.Errorf("Lookup() = %v, %q", n, from)
+	}
+	for _, l := range b.Locales() {
+		if l == "de" || l == "fr" {
+			t.Errorf("Locale %s was not removed: %q", l, b.Locales())
+		}
+	}
+}
+
+func TestBundleCoverage(t *testing.T) {
+	b := testBundle(t)
+	missing, err := b.MissingKeys("de")
+	if err != nil {
+		t.Fatalf("MissingKeys() error: %v", err)
+	}
+	if got, want := missing, [][][]byte{{key("bye")}, {key("menu"), key("close")}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Wrong missing keys\nWant %q\nGot  %q", want, got)
+	}
+	coverage, err := b.Coverage()
+	if err != nil {
+		t.Fatalf("Coverage() error: %v", err)
+	}
+	want := []Coverage{{"en", 4, 4}, {"de", 2, 4}, {"de_AT", 1, 4}}
+	if !reflect.DeepEqual(coverage, want) {
+		t.Errorf("Wrong coverage\nWant %v\nGot  %v", want, coverage)
+	}
+	if got := coverage[2].Percent(); got != 25 {
+		t.Errorf("Wrong percentage %v", got)
+	}
+	if _, err := NewBundle("fr").Coverage(); err == nil || err.Error() != `no tree for the fallback locale "fr"` {
+		t.Errorf("Wrong error %v", err)
+	}
+}
+
+func TestBundleView(t *testing.T) {
+	b := testBundle(t)
+	view := b.View("de-AT")
+	want := `{"de_AT":{"hello":"Servus","bye":"Goodbye","menu":{"open":"Öffnen","close":"Close"},"extra":"x"}}`
+	if got := nodeString(view); got != want {
+		t.Errorf("Wrong view\nWant %s\nGot  %s", want, got)
+	}
+	// Nodes added to the view are added to the tree of the locale
+	menu := view.Nodes()[2]
+	menu.AddNode(key("close")).Value().Deserialize([]byte("Schließen"))
+	want = `{"de_AT":{"hello":"Servus","menu":{"close":"Schließen"}}}`
+	if got := nodeString(b.Tree("de-AT")); got != want {
+		t.Errorf("Wrong tree\nWant %s\nGot  %s", want, got)
+	}
+	if got := nodeString(b.Tree("en")); !strings.Contains(got, `"close":"Close"`) {
+		t.Errorf("Fallback tree changed: %s", got)
+	}
+	if view := b.View("fr"); view.AddNode(key("a")) != nil {
+		t.Errorf("AddNode() added to the fallback tree")
+	}
+	if view := NewBundle("en").View("en"); view != nil {
+		t.Errorf("View() of an empty bundle is not nil")
+	}
+}
@@ -0,0 +1,99 @@
+package jsontree
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Canonicalize returns the canonical serialization of node, as written by
+// SerializeNode with EncodeOptions.Canonical.
+func Canonicalize(node Node) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := (EncodeOptions{Canonical: true}).SerializeNode(node, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// canonicalString returns the canonical escaping of the raw contents of a
+// JSON string
+func canonicalString(raw []byte) ([]byte, error) {
+	s, err := unescapeString(raw)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(s) {
+		return nil, fmt.Errorf("invalid UTF-8 in string \"%s\"", raw)
+	}
+	return appendEscaped(make([]byte, 0, len(s)), s), nil
+}
+
+// sortCanonical returns a copy of nodes sorted by the UTF-16 code units of
+// their unescaped keys
+func sortCanonical(nodes []Node) ([]Node, error) {
+	keys := make([][]uint16, len(nodes))
+	for i, node := range nodes {
+		if node == nil {
+			return nil, fmt.Errorf("invalid node: node.Nodes() contained nil")
+		}
+		key, err := utf16Key(node.Key())
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+	sorted := make([]Node, len(nodes))
+	copy(sorted, nodes)
+	sort.Stable(&utf16Sorter{nodes: sorted, keys: keys})
+	return sorted, nil
+}
+
+type utf16Sorter struct {
+	nodes []Node
+	keys  [][]uint16
+}
+
+func (s *utf16Sorter) Len() int {
+	return len(s.nodes)
+}
+
+func (s *utf16Sorter) Less(i, j int) bool {
+	return compareUTF16(s.keys[i], s.keys[j]) < 0
+}
+
+func (s *utf16Sorter) Swap(i, j int) {
+	s.nodes[i], s.nodes[j] = s.nodes[j], s.nodes[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+// utf16Key returns the UTF-16 code units of the raw key
+func utf16Key(raw []byte) ([]uint16, error) {
+	key, err := unescapeString(raw)
+	if err != nil {
+		return nil, err
+	}
+	return utf16.Encode([]rune(string(key))), nil
+}
+
+// compareUTF16Keys compares two raw keys by their UTF-16 code units. Keys
+// that can not be unescaped compare as empty.
+func compareUTF16Keys(a, b []byte) int {
+	ka, _ := utf16Key(a)
+	kb, _ := utf16Key(b)
+	return compareUTF16(ka, kb)
+}
+
+func compareUTF16(a, b []uint16) int {
+	for k := 0; k < len(a) && k < len(b); k++ {
+		if a[k] != b[k] {
+			if a[k] < b[k] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
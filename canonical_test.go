@@ -0,0 +1,98 @@
+package jsontree
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name string
+		node *testNode
+		want string
+		err  error
+	}{
+		{
+			name: "keys are sorted",
+			node: &testNode{key: key("root"), nodes: []*testNode{
+				{key: key("b"), value: val("2")},
+				{key: key("a"), nodes: []*testNode{
+					{key: key("z"), value: val("3")},
+					{key: key("y"), value: val("4")},
+				}},
+				{key: key("A"), value: val("1")},
+			}},
+			want: `{"root":{"A":"1","a":{"y":"4","z":"3"},"b":"2"}}`,
+		},
+		{
+			name: "keys are sorted by UTF-16 code units",
+			// U+1F600 is encoded as the surrogates D83D DE00, and sorts before U+FB33
+			node: &testNode{key: key("root"), nodes: []*testNode{
+				{key: key("דּ"), value: val("1")},
+				{key: key(`😀`), value: val("2")},
+				{key: key("é"), value: val("3")},
+			}},
+			want: "{\"root\":{\"é\":\"3\",\"\U0001F600\":\"2\",\"דּ\":\"1\"}}",
+		},
+		{
+			name: "minimal escaping",
+			node: &testNode{key: key(`root`), value: val(`\/é\u001F\u0008\"`)},
+			want: "{\"root\":\"/é\\u001f\\b\\\"\"}",
+		},
+		{
+			name: "invalid escape",
+			node: &testNode{key: key("root"), value: val(`\x`)},
+			err:  fmt.Errorf("invalid escape '\\x'"),
+		},
+		{
+			name: "invalid UTF-8",
+			node: &testNode{key: key("root"), value: val("\xff")},
+			err:  fmt.Errorf("invalid UTF-8 in string \"\xff\""),
+		},
+	}
+	for _, test := range tests {
+		got, err := Canonicalize(test.node)
+		if !errEqual(test.err, err) {
+			t.Errorf("%s: Wrong error\nWant %v\nGot  %v", test.name, test.err, err)
+			continue
+		}
+		if test.err == nil && string(got) != test.want {
+			t.Errorf("%s: Canonicalize() = %s, want %s", test.name, got, test.want)
+		}
+	}
+
+	// The tree is not modified
+	node := tests[0].node
+	if k := string(node.nodes[0].key); k != "b" {
+		t.Errorf("Canonicalize() reordered the tree")
+	}
+}
+
+func TestCanonicalWriter(t *testing.T) {
+	// The Writer sorts the top level keys like SerializeNode
+	node := &testNode{key: key("doc"), nodes: []*testNode{
+		{key: key("b"), value: val("1")},
+		{key: key("a"), value: val("2")},
+	}}
+	opts := EncodeOptions{MultipleRoots: true, Canonical: true}
+	var buf bytes.Buffer
+	if err := opts.SerializeNode(node, &buf); err != nil {
+		t.Fatalf("SerializeNode() error: %v", err)
+	}
+	want := buf.String()
+	if want != `{"a":"2","b":"1"}` {
+		t.Errorf("SerializeNode() = %s", want)
+	}
+	buf.Reset()
+	w := opts.NewWriter(&buf)
+	if err := w.WriteNode(node); err != nil {
+		t.Fatalf("WriteNode() error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("Writer wrote %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package jsontree
+
+import "sync"
+
+// ConcurrentNode is a Node safe for concurrent use, keeping its children in
+// the order they were added and its value as raw bytes. The zero value is an
+// empty node.
+//
+// Nodes returns a snapshot of the children, which later changes do not
+// affect. Replace and Swap change whole subtrees at once: a reader, like
+// SerializeNode, sees either the old subtree or the new one, never a mix.
+// Subtrees modified in place rather than replaced offer no such guarantee
+// beyond the level being modified.
+type ConcurrentNode struct {
+	mu    sync.RWMutex
+	key   []byte
+	value []byte
+	nodes []*ConcurrentNode
+}
+
+func (n *ConcurrentNode) Key() []byte {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.key
+}
+
+func (n *ConcurrentNode) SetKey(key []byte) {
+	n.mu.Lock()
+	n.key = key
+	n.mu.Unlock()
+}
+
+// Value returns a Value reading and writing the value of the node under its
+// lock
+func (n *ConcurrentNode) Value() Value {
+	return (*concurrentValue)(n)
+}
+
+func (n *ConcurrentNode) Nodes() []Node {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if len(n.nodes) == 0 {
+		return nil
+	}
+	nodes := make([]Node, len(n.nodes))
+	for i, child := range n.nodes {
+		nodes[i] = child
+	}
+	return nodes
+}
+
+func (n *ConcurrentNode) AddNode(key []byte) Node {
+	node := &ConcurrentNode{key: key}
+	n.mu.Lock()
+	n.nodes = append(n.nodes, node)
+	n.mu.Unlock()
+	return node
+}
+
+// Replace replaces the first child with the key of node by node, or adds
+// node if there is none
+func (n *ConcurrentNode) Replace(node *ConcurrentNode) {
+	key := node.Key()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for i, child := range n.nodes {
+		if keyEqual(child.Key(), key) {
+			n.nodes[i] = node
+			return
+		}
+	}
+	n.nodes = append(n.nodes, node)
+}
+
+// Swap replaces the value and the children of n by those of other at once,
+// as when reloading a tree read into a new ConcurrentNode. The key of n is
+// left as it is. other must not be modified afterwards.
+func (n *ConcurrentNode) Swap(other *ConcurrentNode) {
+	other.mu.RLock()
+	value, nodes := other.value, other.nodes
+	other.mu.RUnlock()
+	n.mu.Lock()
+	n.value, n.nodes = value, nodes
+	n.mu.Unlock()
+}
+
+// Remove removes the children with key, and reports whether there were any
+func (n *ConcurrentNode) Remove(key []byte) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	nodes := make([]*ConcurrentNode, 0, len(n.nodes))
+	for _, child := range n.nodes {
+		if !keyEqual(child.Key(), key) {
+			nodes = append(nodes, child)
+		}
+	}
+	removed := len(nodes) < len(n.nodes)
+	n.nodes = nodes
+	return removed
+}
+
+type concurrentValue ConcurrentNode
+
+func (v *concurrentValue) Serialize() ([]byte, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.value, nil
+}
+
+func (v *concurrentValue) Deserialize(b []byte) error {
+	v.mu.Lock()
+	v.value = b
+	v.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,117 @@
+package jsontree
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentNode(t *testing.T) {
+	node := new(ConcurrentNode)
+	in := `{"root":{"a":"1","b":{"c":"2"}}}`
+	if err := DeserializeNode(node, strings.NewReader(in)); err != nil {
+		t.Fatalf("DeserializeNode() error: %v", err)
+	}
+	if got := nodeString(node); got != in {
+		t.Errorf("Wrong tree\nWant %s\nGot  %s", in, got)
+	}
+
+	// Nodes returns a snapshot
+	snapshot := node.Nodes()
+	node.AddNode(key("d")).Value().Deserialize([]byte("3"))
+	if len(snapshot) != 2 || len(node.Nodes()) != 3 {
+		t.Errorf("Wrong number of nodes: %d in the snapshot, %d in the node", len(snapshot), len(node.Nodes()))
+	}
+
+	b := &ConcurrentNode{key: key("b")}
+	b.AddNode(key("x")).Value().Deserialize([]byte("y"))
+	node.Replace(b)
+	node.Replace(&ConcurrentNode{key: key("e"), value: []byte("4")})
+	if !node.Remove(key("a")) || node.Remove(key("missing")) {
+		t.Errorf("Remove() reported the wrong result")
+	}
+	want := `{"root":{"b":{"x":"y"},"d":"3","e":"4"}}`
+	if got := nodeString(node); got != want {
+		t.Errorf("Wrong tree\nWant %s\nGot  %s", want, got)
+	}
+
+	reloaded := new(ConcurrentNode)
+	if err := DeserializeNode(reloaded, strings.NewReader(`{"new":{"z":"1"}}`)); err != nil {
+		t.Fatalf("DeserializeNode() error: %v", err)
+	}
+	node.Swap(reloaded)
+	want = `{"root":{"z":"1"}}`
+	if got := nodeString(node); got != want {
+		t.Errorf("Wrong tree\nWant %s\nGot  %s", want, got)
+	}
+}
+
+// TestConcurrentNodeRace is meant to be run with the race detector: readers
+// serialize the tree while writers reload and modify it.
+func TestConcurrentNodeRace(t *testing.T) {
+	root := new(ConcurrentNode)
+	load := func(version int) *ConcurrentNode {
+		n := new(ConcurrentNode)
+		doc := fmt.Sprintf(`{"config":{"version":"%d","server":{"host":"h%d","port":"%d"}}}`, version, version, version)
+		if err := DeserializeNode(n, strings.NewReader(doc)); err != nil {
+			t.Error(err)
+		}
+		return n
+	}
+	root.Swap(load(0))
+	root.SetKey(key("config"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				var buf bytes.Buffer
+				if err := SerializeNode(root, &buf); err != nil {
+					t.Error(err)
+					return
+				}
+				// Subtrees are replaced whole, so the server is never torn
+				s := buf.String()
+				var host, port string
+				if i := strings.Index(s, `"host":"h`); i >= 0 {
+					host = s[i+len(`"host":"h`):]
+					host = host[:strings.IndexByte(host, '"')]
+				}
+				if i := strings.Index(s, `"port":"`); i >= 0 {
+					port = s[i+len(`"port":"`):]
+					port = port[:strings.IndexByte(port, '"')]
+				}
+				if host != port {
+					t.Errorf("Torn server: %s", s)
+					return
+				}
+			}
+		}()
+	}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for v := 1; v <= 100; v++ {
+			if v%2 == 0 {
+				root.Swap(load(v))
+			} else {
+				root.Replace(getNode(load(v), key("server")).(*ConcurrentNode))
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 100; j++ {
+			n := root.AddNode(key(fmt.Sprintf("k%d", j)))
+			n.Value().Deserialize([]byte("v"))
+			root.Remove(n.Key())
+			root.Value().Serialize()
+			root.Key()
+		}
+	}()
+	wg.Wait()
+}
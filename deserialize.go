@@ -2,6 +2,7 @@ package jsontree
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -21,14 +22,49 @@ func (err *DeserializeError) Error() string {
 	return fmt.Sprintf("Read '%s', expected '%s'", string(err.Got), wantStr)
 }
 
+// Position is a location in a document. Offset is zero based, Line and Column
+// are one based. Column counts bytes, not runes.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+func (pos Position) String() string {
+	return fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+}
+
+func (pos *Position) advance(b byte) {
+	pos.Offset++
+	if b == '\n' {
+		pos.Line++
+		pos.Column = 1
+	} else {
+		pos.Column++
+	}
+}
+
 func DeserializeNode(node Node, r io.Reader) error {
-	p := newParser(r)
+	return DecodeOptions{}.DeserializeNode(node, r)
+}
+
+func deserializeNode(node Node, p *parser) error {
+	if tn, ok := node.(TriviaNode); ok {
+		p.onTrivia = triviaSetter(tn, p.opts.MultipleRoots)
+	}
 	isKeySet := false
+	var cache pathCache
 	for p.Scan() {
 		path, valBytes := p.Data()
+		if p.opts.MultipleRoots {
+			if err := cache.getOrAdd(node, path).Value().Deserialize(valBytes); err != nil {
+				return p.abort(err)
+			}
+			continue
+		}
 		n := len(path)
 		if n == 1 && isKeySet {
-			return fmt.Errorf("invalid json. Expected 1 root node")
+			return p.abort(fmt.Errorf("invalid json. Expected 1 root node"))
 		}
 		if !isKeySet {
 			node.SetKey(path[0])
@@ -38,41 +74,144 @@ func DeserializeNode(node Node, r io.Reader) error {
 		if n == 1 {
 			value = node.Value()
 		} else {
-			child := getOrAddNode(node, path[1:]...)
+			child := cache.getOrAdd(node, path[1:])
 			value = child.Value()
 		}
 		if err := value.Deserialize(valBytes); err != nil {
-			return err
+			return p.abort(err)
 		}
 	}
-	return p.Err()
+	if err := p.Err(); err != nil || p.opts.Schema == nil {
+		return err
+	}
+	return p.opts.Schema.Validate(node)
 }
 
-type readFn func() (next readFn, err error)
+type readFn func(p *parser) (next readFn, err error)
 
 type ReadPeeker interface {
 	ReadByte() (byte, error)
 	Peek(int) ([]byte, error)
 }
 
+// source is the input the parser reads its grammar from. readString reads
+// the contents of a string quoted by quote, after the opening quote has been
+// consumed, up to and including the closing quote. If max > 0 and the contents are
+// longer than max bytes, it returns errTooLong. position returns the position
+// of the next byte to be read, lastPosition that of the last byte read.
+type source interface {
+	ReadPeeker
+	readString(quote byte, max int) ([]byte, error)
+	position() Position
+	lastPosition() Position
+}
+
+var errTooLong = errors.New("string too long")
+
+type readerSource struct {
+	r        ReadPeeker
+	pos      Position
+	last     Position // position of the last byte read
+	maxBytes int      // if > 0, reading beyond this offset fails with a LimitError
+}
+
+func newReaderSource(r io.Reader) *readerSource {
+	s := &readerSource{pos: Position{Line: 1, Column: 1}}
+	if rp, ok := r.(ReadPeeker); ok {
+		s.r = rp
+	} else {
+		s.r = bufio.NewReader(r)
+	}
+	return s
+}
+
+func (s *readerSource) ReadByte() (byte, error) {
+	if s.maxBytes > 0 && s.pos.Offset >= s.maxBytes {
+		// Only report the limit if there is actually more input
+		if _, err := s.r.Peek(1); err != nil {
+			return 0, err
+		}
+		return 0, &LimitError{Limit: "MaxBytes", Max: s.maxBytes}
+	}
+	b, err := s.r.ReadByte()
+	if err == nil {
+		s.last = s.pos
+		s.pos.advance(b)
+	}
+	return b, err
+}
+
+func (s *readerSource) Peek(n int) ([]byte, error) {
+	return s.r.Peek(n)
+}
+
+func (s *readerSource) position() Position {
+	return s.pos
+}
+
+func (s *readerSource) lastPosition() Position {
+	return s.last
+}
+
+func (s *readerSource) readString(quote byte, max int) ([]byte, error) {
+	bs := []byte{}
+	for {
+		if max > 0 && len(bs) > max {
+			return nil, errTooLong
+		}
+		b, err := s.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == '\\' { // escape
+			bs = append(bs, b)
+			if b, err := s.ReadByte(); err != nil {
+				return nil, err
+			} else {
+				bs = append(bs, b)
+			}
+			continue
+		}
+		if b == quote {
+			break
+		}
+		bs = append(bs, b)
+	}
+	return bs, nil
+}
+
 type parser struct {
-	r     ReadPeeker
+	r     source
+	opts  DecodeOptions
 	next  readFn
 	err   error
 	mode  int
 	path  stack
 	value []byte
 	eof   bool
+	nodes int
+	// stream stops the parser after the root object without requiring the
+	// end of the input
+	stream bool
+	seen   []map[string]Position // keys of the open objects, when tracking duplicates
+	skip   int                   // if > 0, values at this depth or deeper are ignored
+	depth  int                   // number of open objects
+	// errPos is the position of the byte that caused the last syntax error,
+	// and errRead whether that byte was consumed
+	errPos  Position
+	errRead bool
+	errors  MultiError
+	// onTrivia, if set, is passed the whitespace around the tokens of the
+	// document
+	onTrivia   func(path [][]byte, kind TriviaKind, trivia []byte)
+	afterComma bool // whether the last token was a comma
 }
 
-func newParser(r io.Reader) *parser {
+func newParser(r source, opts DecodeOptions) *parser {
 	p := new(parser)
-	if rp, ok := r.(ReadPeeker); ok {
-		p.r = rp
-	} else {
-		p.r = bufio.NewReader(r)
-	}
-	p.next = p.readOpenBracket
+	p.r = r
+	p.opts = opts
+	p.next = (*parser).readOpenBracket
 	return p
 }
 
@@ -83,10 +222,16 @@ func (p *parser) Scan() bool {
 	p.value = nil
 	// Scan until we've hit a value
 	for p.value == nil {
-		p.next, p.err = p.next()
+		p.next, p.err = p.next(p)
 		if p.err != nil {
+			if p.opts.RecoverErrors && p.recover() {
+				continue
+			}
 			return false
 		}
+		if p.skip > 0 && p.value != nil {
+			p.value = nil
+		}
 	}
 	return true
 }
@@ -98,6 +243,19 @@ func (p *parser) Data() (path [][]byte, valueBytes []byte) {
 }
 
 func (p *parser) Err() error {
+	err := p.scanErr()
+	if len(p.errors) == 0 {
+		return err
+	}
+	// Recovered errors are returned along with the error that ended the scan
+	errs := p.errors[:len(p.errors):len(p.errors)]
+	if err != nil {
+		errs = append(errs, &SyntaxError{Path: append([][]byte(nil), p.path...), Pos: p.r.position(), Err: err})
+	}
+	return errs
+}
+
+func (p *parser) scanErr() error {
 	if p.err == io.EOF {
 		if p.eof {
 			return nil
@@ -109,127 +267,293 @@ func (p *parser) Err() error {
 	}
 }
 
+func (p *parser) openObject() {
+	p.depth++
+	if p.trackDuplicates() {
+		p.seen = append(p.seen, make(map[string]Position))
+	}
+}
+
+func (p *parser) closeObject() {
+	p.depth--
+	if n := len(p.seen); n > 0 {
+		p.seen = p.seen[:n-1]
+	}
+}
+
 func (p *parser) readByte(bWant byte, next readFn) (readFn, error) {
 	if bGot, err := p.r.ReadByte(); err != nil {
 		return nil, err
 	} else if bGot != bWant {
+		p.errPos, p.errRead = p.r.lastPosition(), true
 		return nil, &DeserializeError{Got: bGot, Want: []byte{bWant}}
 	} else {
 		return next, nil
 	}
 }
 
+// unexpected returns the error for an unexpected byte that has been peeked
+func (p *parser) unexpected(got byte, want ...byte) error {
+	p.errPos, p.errRead = p.r.position(), false
+	return &DeserializeError{Got: got, Want: want}
+}
+
 func (p *parser) readOpenBracket() (readFn, error) {
-	return p.readByte('{', p.readQuotedKey)
+	b, trivia, err := p.readByteSkipSpace()
+	if err != nil {
+		return nil, err
+	}
+	p.trivia(TriviaDocStart, trivia)
+	if b != '{' {
+		p.errPos, p.errRead = p.r.lastPosition(), true
+		return nil, &DeserializeError{Got: b, Want: []byte{'{'}}
+	}
+	p.openObject()
+	b, trivia, err = p.space()
+	if err != nil {
+		return nil, err
+	}
+	if p.opts.MultipleRoots && b == '}' {
+		// The top level object may be empty
+		p.trivia(TriviaClosing, trivia)
+		return (*parser).readCloseBracket, nil
+	}
+	return p.readKey(b, trivia)
 }
 
 func (p *parser) readCloseBracket() (readFn, error) {
 	if _, err := p.readByte('}', nil); err != nil {
 		return nil, err
 	}
+	return p.closeBracket()
+}
+
+// closeBracket is called after a closing bracket has been read
+func (p *parser) closeBracket() (readFn, error) {
+	p.closeObject()
 	p.path.Pop()
 	if len(p.path) == 0 {
 		p.eof = true
-		if b, err := p.r.ReadByte(); err == nil {
+		if p.stream {
+			return nil, io.EOF
+		}
+		b, trivia, err := p.readByteSkipSpace()
+		p.trivia(TriviaDocEnd, trivia)
+		if err == nil {
 			return nil, fmt.Errorf("expected end of input. Got '%s'", string(b))
 		} else {
 			return nil, err
 		}
 	}
-	if bs, err := p.r.Peek(1); err != nil {
+	return p.readEndOfEntry()
+}
+
+// readEndOfEntry peeks at what follows the value of an entry, which is either
+// a sibling entry or a closing bracket
+func (p *parser) readEndOfEntry() (readFn, error) {
+	b, trivia, err := p.space()
+	if err != nil {
 		return nil, err
-	} else {
-		switch bs[0] {
-		case '}':
-			return p.readCloseBracket, nil
-		case ',':
-			return p.readComma, nil
-		default:
-			return nil, &DeserializeError{Got: bs[0], Want: []byte{'}', ','}}
+	}
+	switch b {
+	case '}':
+		// Trivia before a closing bracket belongs to the object, so that
+		// it stays in place when entries are added
+		if len(p.path) == 1 && !p.opts.MultipleRoots {
+			p.trivia(TriviaTrailing, trivia) // the document only holds the root
+		} else if p.onTrivia != nil && len(trivia) > 0 && p.skip == 0 {
+			p.onTrivia(p.path[:len(p.path)-1], TriviaClosing, trivia)
 		}
+		return (*parser).readCloseBracket, nil
+	case ',':
+		p.trivia(TriviaTrailing, trivia)
+		return (*parser).readComma, nil
+	default:
+		return nil, p.unexpected(b, '}', ',')
 	}
 }
 
-func (p *parser) readQuotedString() ([]byte, error) {
-	if _, err := p.readByte('"', nil); err != nil {
+func (p *parser) readQuotedString(limit string, max int) ([]byte, error) {
+	quote := byte('"')
+	if p.opts.Dialect == DialectJSON5 {
+		if bs, err := p.r.Peek(1); err == nil && bs[0] == '\'' {
+			quote = '\''
+		}
+	}
+	if _, err := p.readByte(quote, nil); err != nil {
 		return nil, err
 	}
-	var bs []byte
-	for {
-		b, err := p.r.ReadByte()
-		if err != nil {
-			return nil, err
-		}
-		if b == '\\' { // escape
-			bs = append(bs, b)
-			if b, err := p.r.ReadByte(); err != nil {
-				return nil, err
-			} else {
-				bs = append(bs, b)
-			}
-			continue
-		}
-		if b == '"' {
-			break
-		}
-		bs = append(bs, b)
+	bs, err := p.r.readString(quote, max)
+	if err == errTooLong {
+		return nil, &LimitError{Limit: limit, Max: max}
 	}
-	return bs, nil
+	if err == nil && quote == '\'' {
+		bs = singleToDoubleQuoted(bs)
+	}
+	return bs, err
 }
 
 func (p *parser) readQuotedKey() (readFn, error) {
-	if bs, err := p.readQuotedString(); err != nil {
+	b, trivia, err := p.space()
+	if err != nil {
+		return nil, err
+	}
+	if b == '}' && p.opts.Dialect != DialectJSON && p.afterComma {
+		// A trailing comma. Stand in for the key the closing bracket pops.
+		if len(p.path) > 0 || p.opts.MultipleRoots {
+			p.trivia(TriviaClosing, trivia)
+		}
+		p.path.Push(nil)
+		return (*parser).readCloseBracket, nil
+	}
+	return p.readKey(b, trivia)
+}
+
+// readKey reads a key and what follows it, up to its value. next is the
+// first byte of the key, still to be read, and leading the trivia preceding
+// it.
+func (p *parser) readKey(next byte, leading []byte) (readFn, error) {
+	var pos Position
+	if p.trackDuplicates() {
+		pos = p.r.position()
+	}
+	p.afterComma = false
+	if p.opts.Dialect == DialectJSON5 && isIdentifierStart(next) {
+		if bs, err := p.readIdentifier(p.opts.MaxKeyLen); err != nil {
+			return nil, err
+		} else {
+			p.path.Push(bs)
+		}
+	} else if bs, err := p.readQuotedString("MaxKeyLen", p.opts.MaxKeyLen); err != nil {
 		return nil, err
 	} else {
 		p.path.Push(bs)
 	}
+	if max := p.opts.MaxDepth; max > 0 && len(p.path) > max {
+		return nil, &LimitError{Limit: "MaxDepth", Max: max}
+	}
+	p.nodes++
+	if max := p.opts.MaxNodes; max > 0 && p.nodes > max {
+		return nil, &LimitError{Limit: "MaxNodes", Max: max}
+	}
+	if err := p.checkDuplicate(pos); err != nil {
+		return nil, err
+	}
+	p.trivia(TriviaLeading, leading)
 	// Following the key should be a column
+	if _, trivia, err := p.space(); err != nil {
+		return nil, err
+	} else {
+		p.trivia(TriviaBeforeColon, trivia)
+	}
 	if _, err := p.readByte(':', nil); err != nil {
 		return nil, err
 	}
 	// And following the column is either a sub node or a value
-	if bs, err := p.r.Peek(1); err != nil {
+	b, trivia, err := p.space()
+	if err != nil {
 		return nil, err
-	} else {
-		switch bs[0] {
-		case '{':
-			// Consume the byte
-			if _, err := p.r.ReadByte(); err != nil {
-				return nil, err
-			}
-			return p.readQuotedKey, nil
-		case '"':
-			return p.readQuotedValue, nil
-		default:
-			return nil, &DeserializeError{Got: bs[0], Want: []byte{'{', '"'}}
+	}
+	p.trivia(TriviaAfterColon, trivia)
+	switch b {
+	case '{':
+		// Consume the byte
+		if _, err := p.r.ReadByte(); err != nil {
+			return nil, err
 		}
+		p.openObject()
+		return (*parser).readQuotedKey, nil
+	case '"':
+		return (*parser).readQuotedValue, nil
+	case '\'':
+		if p.opts.Dialect == DialectJSON5 {
+			return (*parser).readQuotedValue, nil
+		}
+		return nil, p.unexpected(b, '{', '"')
+	default:
+		return nil, p.unexpected(b, '{', '"')
 	}
 }
 
 func (p *parser) readQuotedValue() (readFn, error) {
-	if bs, err := p.readQuotedString(); err != nil {
+	if bs, err := p.readQuotedString("MaxValueLen", p.opts.MaxValueLen); err != nil {
 		return nil, err
 	} else {
 		p.value = bs
 	}
-	// Following the value is either a sibling node or a closing bracket
-	if bs, err := p.r.Peek(1); err != nil {
+	return p.readEndOfEntry()
+}
+
+func (p *parser) readComma() (readFn, error) {
+	if _, err := p.readByte(',', nil); err != nil {
 		return nil, err
-	} else {
-		switch bs[0] {
-		case '}':
-			return p.readCloseBracket, nil
-		case ',':
-			return p.readComma, nil
-		default:
-			return nil, &DeserializeError{Got: bs[0], Want: []byte{'}', ','}}
+	}
+	p.path.Pop()
+	p.afterComma = true
+	return (*parser).readQuotedKey, nil
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
+
+// space skips whitespace, and comments if the dialect has them, and returns
+// the byte following it without consuming it. What was skipped is returned
+// if trivia is being captured.
+func (p *parser) space() (next byte, trivia []byte, err error) {
+	for {
+		bs, err := p.r.Peek(1)
+		if err != nil {
+			return 0, trivia, err
+		}
+		b := bs[0]
+		if b == '/' && p.opts.Dialect != DialectJSON {
+			var isComment bool
+			if isComment, trivia, err = p.readComment(trivia); err != nil {
+				return 0, trivia, err
+			} else if isComment {
+				continue
+			}
+		}
+		if !isSpace(b) {
+			return b, trivia, nil
+		}
+		if _, err := p.r.ReadByte(); err != nil {
+			return 0, trivia, err
+		}
+		if p.onTrivia != nil {
+			trivia = append(trivia, b)
 		}
 	}
 }
 
-func (p *parser) readComma() (readFn, error) {
-	p.path.Pop()
-	return p.readByte(',', p.readQuotedKey)
+// readByteSkipSpace is like space, but consumes the byte following the
+// whitespace. It is used where the input may end, and there is no need to
+// peek.
+func (p *parser) readByteSkipSpace() (b byte, trivia []byte, err error) {
+	if p.opts.Dialect != DialectJSON {
+		// Comments can only be detected by peeking
+		if _, trivia, err = p.space(); err != nil {
+			return 0, trivia, err
+		}
+		b, err = p.r.ReadByte()
+		return b, trivia, err
+	}
+	for {
+		if b, err = p.r.ReadByte(); err != nil || !isSpace(b) {
+			return b, trivia, err
+		}
+		if p.onTrivia != nil {
+			trivia = append(trivia, b)
+		}
+	}
+}
+
+// trivia passes trivia of the current path to p.onTrivia
+func (p *parser) trivia(kind TriviaKind, trivia []byte) {
+	if p.onTrivia != nil && len(trivia) > 0 && p.skip == 0 {
+		p.onTrivia(p.path, kind, trivia)
+	}
 }
 
 type stack [][]byte
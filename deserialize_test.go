@@ -92,6 +92,15 @@ func TestDeserializeNode(t *testing.T) {
 				{key: key(`b}`), value: val(`\\backslash\nnewline`)},
 			}},
 		},
+		// Whitespace between tokens
+		{
+			in:    " {\n\t\"root\" : {\"a\":\"b\" ,\r\n \"c\": {\"d\": \"e\"} }\n}\n",
+			weird: true,
+			want: &testNode{key: key("root"), nodes: []*testNode{
+				{key: key("a"), value: val("b")},
+				{key: key("c"), nodes: []*testNode{{key: key("d"), value: val("e")}}},
+			}},
+		},
 		// Handling invalid input. See also section Test unexpected tokens (invalid JSON) below
 		// -- JSON syntax error
 		{
@@ -227,6 +236,59 @@ func BenchmarkNodeDeserialization3(b *testing.B) { benchmarkNodeDeserialization(
 func BenchmarkNodeDeserialization4(b *testing.B) { benchmarkNodeDeserialization(4, b) }
 func BenchmarkNodeDeserialization5(b *testing.B) { benchmarkNodeDeserialization(5, b) }
 
+func benchmarkBytesDeserialization(n int, b *testing.B) {
+	node := getTestNode(n, n)
+	var buf bytes.Buffer
+	if err := SerializeNode(node, &buf); err != nil {
+		panic(err)
+	}
+	data := buf.Bytes()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := DeserializeBytes(new(testNode), data); err != nil {
+			b.Fatalf("Error: %v", err)
+		}
+	}
+}
+
+func BenchmarkBytesDeserialization1(b *testing.B) { benchmarkBytesDeserialization(1, b) }
+func BenchmarkBytesDeserialization2(b *testing.B) { benchmarkBytesDeserialization(2, b) }
+func BenchmarkBytesDeserialization3(b *testing.B) { benchmarkBytesDeserialization(3, b) }
+func BenchmarkBytesDeserialization4(b *testing.B) { benchmarkBytesDeserialization(4, b) }
+func BenchmarkBytesDeserialization5(b *testing.B) { benchmarkBytesDeserialization(5, b) }
+
+// The scan benchmarks measure the parsers alone, without the cost of building a tree
+func benchmarkScan(n int, fromBytes bool, b *testing.B) {
+	node := getTestNode(n, n)
+	var buf bytes.Buffer
+	if err := SerializeNode(node, &buf); err != nil {
+		panic(err)
+	}
+	data := buf.Bytes()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var p *parser
+		if fromBytes {
+			p = newParser(newSliceSource(data), DecodeOptions{})
+		} else {
+			p = newParser(newReaderSource(bytes.NewReader(data)), DecodeOptions{})
+		}
+		for p.Scan() {
+		}
+		if err := p.Err(); err != nil {
+			b.Fatalf("Error: %v", err)
+		}
+	}
+}
+
+func BenchmarkReaderScan3(b *testing.B) { benchmarkScan(3, false, b) }
+func BenchmarkReaderScan5(b *testing.B) { benchmarkScan(5, false, b) }
+func BenchmarkBytesScan3(b *testing.B)  { benchmarkScan(3, true, b) }
+func BenchmarkBytesScan5(b *testing.B)  { benchmarkScan(5, true, b) }
+
 // ========== Utility ==========
 
 func nodeString(node Node) string {
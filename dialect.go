@@ -0,0 +1,136 @@
+package jsontree
+
+import (
+	"bytes"
+	"io"
+)
+
+// Dialect is the syntax accepted by the parser
+type Dialect int
+
+const (
+	// DialectJSON is plain JSON
+	DialectJSON Dialect = iota
+	// DialectJSONC adds // and /* */ comments, and trailing commas
+	DialectJSONC
+	// DialectJSON5 adds unquoted keys and single quoted strings to
+	// DialectJSONC. Values must still be strings.
+	DialectJSON5
+)
+
+// readComment reads a comment, if the next bytes start one, and appends it to
+// trivia if trivia is being captured
+func (p *parser) readComment(trivia []byte) (isComment bool, _ []byte, err error) {
+	bs, err := p.r.Peek(2)
+	if len(bs) < 2 || (bs[1] != '/' && bs[1] != '*') {
+		return false, trivia, nil
+	}
+	block := bs[1] == '*'
+	var prev byte
+	for i := 0; ; i++ {
+		b, err := p.r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				if !block {
+					return true, trivia, nil // a line comment may end the input
+				}
+				err = io.ErrUnexpectedEOF
+			}
+			return true, trivia, err
+		}
+		if p.onTrivia != nil {
+			trivia = append(trivia, b)
+		}
+		if block && i > 2 && prev == '*' && b == '/' {
+			return true, trivia, nil
+		}
+		if !block && b == '\n' {
+			return true, trivia, nil
+		}
+		prev = b
+	}
+}
+
+func isIdentifierStart(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || b == '_' || b == '$'
+}
+
+func isIdentifierPart(b byte) bool {
+	return isIdentifierStart(b) || (b >= '0' && b <= '9')
+}
+
+// readIdentifier reads an unquoted JSON5 key
+func (p *parser) readIdentifier(max int) ([]byte, error) {
+	var bs []byte
+	for {
+		peek, err := p.r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		b := peek[0]
+		if !isIdentifierPart(b) {
+			return bs, nil
+		}
+		if max > 0 && len(bs) >= max {
+			return nil, &LimitError{Limit: "MaxKeyLen", Max: max}
+		}
+		if _, err := p.r.ReadByte(); err != nil {
+			return nil, err
+		}
+		bs = append(bs, b)
+	}
+}
+
+// singleToDoubleQuoted converts the contents of a single quoted string to
+// those of a double quoted one
+func singleToDoubleQuoted(bs []byte) []byte {
+	if bytes.IndexByte(bs, '"') < 0 && !bytes.Contains(bs, []byte(`\'`)) {
+		return bs
+	}
+	converted := make([]byte, 0, len(bs)+2)
+	for i := 0; i < len(bs); i++ {
+		switch b := bs[i]; {
+		case b == '\\' && i+1 < len(bs) && bs[i+1] == '\'':
+			converted = append(converted, '\'')
+			i++
+		case b == '\\' && i+1 < len(bs):
+			converted = append(converted, b, bs[i+1])
+			i++
+		case b == '"':
+			converted = append(converted, '\\', '"')
+		default:
+			converted = append(converted, b)
+		}
+	}
+	return converted
+}
+
+// stripComments returns trivia without its comments
+func stripComments(trivia []byte) []byte {
+	if bytes.IndexByte(trivia, '/') < 0 {
+		return trivia
+	}
+	stripped := make([]byte, 0, len(trivia))
+	for i := 0; i < len(trivia); i++ {
+		if trivia[i] == '/' && i+1 < len(trivia) {
+			switch trivia[i+1] {
+			case '/':
+				end := bytes.IndexByte(trivia[i:], '\n')
+				if end < 0 {
+					return stripped
+				}
+				i += end - 1 // keep the newline
+				continue
+			case '*':
+				end := bytes.Index(trivia[i+2:], []byte("*/"))
+				if end < 0 {
+					return stripped
+				}
+				i += end + 3
+				continue
+			}
+		}
+		stripped = append(stripped, trivia[i])
+	}
+	return stripped
+}
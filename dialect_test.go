@@ -0,0 +1,155 @@
+package jsontree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDialect(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		dialect Dialect
+		want    string // serialized result, empty if an error is expected
+	}{
+		{
+			name:    "line comments",
+			in:      "// header\n{\"root\": { // first\n\"a\": \"1\" // trailing\n}}\n// end",
+			dialect: DialectJSONC,
+			want:    `{"root":{"a":"1"}}`,
+		},
+		{
+			name:    "block comments",
+			in:      `/* a */{/* b */"root"/* c */:/* d */{"a"/**/:"1"}/* e */}/* f */`,
+			dialect: DialectJSONC,
+			want:    `{"root":{"a":"1"}}`,
+		},
+		{
+			name:    "trailing commas",
+			in:      `{"root":{"a":"1","b":{"c":"2",},}}`,
+			dialect: DialectJSONC,
+			want:    `{"root":{"a":"1","b":{"c":"2"}}}`,
+		},
+		{
+			name:    "unterminated block comment",
+			in:      `{"root":{"a":"1"}} /* end`,
+			dialect: DialectJSONC,
+		},
+		{
+			name:    "comment in strict JSON",
+			in:      `{"root":{"a":"1"}} // end`,
+			dialect: DialectJSON,
+		},
+		{
+			name:    "trailing comma in strict JSON",
+			in:      `{"root":{"a":"1",}}`,
+			dialect: DialectJSON,
+		},
+		{
+			name:    "empty object after trailing comma",
+			in:      `{"root":{,}}`,
+			dialect: DialectJSONC,
+		},
+		{
+			name:    "unquoted keys",
+			in:      `{root:{$a:"1",_b2:{c:"2"}}}`,
+			dialect: DialectJSON5,
+			want:    `{"root":{"$a":"1","_b2":{"c":"2"}}}`,
+		},
+		{
+			name:    "single quotes",
+			in:      `{'root':{'a':'it\'s "quoted"','b':"\""}}`,
+			dialect: DialectJSON5,
+			want:    `{"root":{"a":"it's \"quoted\"","b":"\""}}`,
+		},
+		{
+			name:    "unquoted keys in JSONC",
+			in:      `{root:{a:"1"}}`,
+			dialect: DialectJSONC,
+		},
+		{
+			name:    "single quotes in JSONC",
+			in:      `{"root":{"a":'1'}}`,
+			dialect: DialectJSONC,
+		},
+	}
+	for _, test := range tests {
+		for _, fromBytes := range []bool{false, true} {
+			node := &testNode{}
+			opts := DecodeOptions{Dialect: test.dialect}
+			var err error
+			if fromBytes {
+				err = opts.DeserializeBytes(node, []byte(test.in))
+			} else {
+				err = opts.DeserializeNode(node, strings.NewReader(test.in))
+			}
+			if test.want == "" {
+				if err == nil {
+					t.Errorf("%s: Expected an error", test.name)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s: Unexpected error: %v", test.name, err)
+				continue
+			}
+			var buf bytes.Buffer
+			if err := SerializeNode(node, &buf); err != nil {
+				t.Errorf("%s: SerializeNode() error: %v", test.name, err)
+			} else if got := buf.String(); got != test.want {
+				t.Errorf("%s: Wrong result\nWant %s\nGot  %s", test.name, test.want, got)
+			}
+		}
+	}
+}
+
+func TestDialectComments(t *testing.T) {
+	in := "// settings\n{\n  /* the editor */\n  \"editor\": {\n    \"tabs\": \"4\", // spaces\n  },\n}\n"
+	for _, fromBytes := range []bool{false, true} {
+		node := new(DocumentNode)
+		opts := DecodeOptions{Dialect: DialectJSONC, MultipleRoots: true}
+		var err error
+		if fromBytes {
+			err = opts.DeserializeBytes(node, []byte(in))
+		} else {
+			err = opts.DeserializeNode(node, strings.NewReader(in))
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := (EncodeOptions{MultipleRoots: true}).SerializeNode(node, &buf); err != nil {
+			t.Fatalf("SerializeNode() error: %v", err)
+		}
+		// Trailing commas are dropped, comments are kept
+		want := "// settings\n{\n  /* the editor */\n  \"editor\": {\n    \"tabs\": \"4\" // spaces\n  }\n}\n"
+		if got := buf.String(); got != want {
+			t.Errorf("Wrong result\nWant %q\nGot  %q", want, got)
+		}
+		buf.Reset()
+		if err := (EncodeOptions{MultipleRoots: true, OmitComments: true}).SerializeNode(node, &buf); err != nil {
+			t.Fatalf("SerializeNode() error: %v", err)
+		}
+		want = "\n{\n  \n  \"editor\": {\n    \"tabs\": \"4\" \n  }\n}\n"
+		if got := buf.String(); got != want {
+			t.Errorf("OmitComments: Wrong result\nWant %q\nGot  %q", want, got)
+		}
+	}
+}
+
+func TestStripComments(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{" \n ", " \n "},
+		{" // a\n ", " \n "},
+		{" // a", " "},
+		{"/* a */ /* b\n */", " "},
+		{"/* a", ""},
+	}
+	for _, test := range tests {
+		if got := string(stripComments([]byte(test.in))); got != test.want {
+			t.Errorf("stripComments(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
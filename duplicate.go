@@ -0,0 +1,88 @@
+package jsontree
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// DuplicatePolicy decides what happens when an object contains the same key
+// more than once.
+type DuplicatePolicy int
+
+const (
+	// DuplicateLastWins merges duplicate keys. Leaf values are deserialized
+	// in document order, so the last one wins.
+	DuplicateLastWins DuplicatePolicy = iota
+	// DuplicateFirstWins ignores the value of every occurrence of a key but the first.
+	DuplicateFirstWins
+	// DuplicateError fails with a *DuplicateKeyError.
+	DuplicateError
+	// DuplicateCallback calls DecodeOptions.OnDuplicate to pick one of the other policies.
+	DuplicateCallback
+)
+
+// DuplicateKeyError describes a duplicate key. Path is the path of the
+// duplicate key, First and Second the positions of the two occurrences.
+type DuplicateKeyError struct {
+	Path   [][]byte
+	First  Position
+	Second Position
+}
+
+func (err *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("duplicate key \"%s\" at %s, first defined at %s", bytes.Join(err.Path, []byte{'.'}), err.Second, err.First)
+}
+
+// trackDuplicates reports whether the parser needs to keep track of keys
+func (p *parser) trackDuplicates() bool {
+	return p.opts.Duplicates != DuplicateLastWins
+}
+
+// checkDuplicate is called after a key at pos has been pushed to p.path
+func (p *parser) checkDuplicate(pos Position) error {
+	depth := len(p.path)
+	if p.skip > 0 && depth <= p.skip {
+		// A sibling of the skipped key or one of its parents
+		p.skip = 0
+	}
+	if !p.trackDuplicates() || len(p.seen) == 0 {
+		return nil
+	}
+	seen := p.seen[len(p.seen)-1]
+	key := string(p.path[depth-1])
+	first, ok := seen[key]
+	if !ok {
+		seen[key] = pos
+		return nil
+	}
+	dup := &DuplicateKeyError{
+		Path:   append([][]byte(nil), p.path...),
+		First:  first,
+		Second: pos,
+	}
+	policy, err := p.opts.duplicatePolicy(dup)
+	if policy == DuplicateFirstWins {
+		p.skip = depth
+	}
+	return err
+}
+
+// duplicatePolicy returns the policy for dup: DuplicateLastWins or
+// DuplicateFirstWins, or an error
+func (opts *DecodeOptions) duplicatePolicy(dup *DuplicateKeyError) (DuplicatePolicy, error) {
+	policy := opts.Duplicates
+	if policy == DuplicateCallback {
+		if opts.OnDuplicate == nil {
+			return 0, fmt.Errorf("DecodeOptions.OnDuplicate must be set when using DuplicateCallback")
+		}
+		policy = opts.OnDuplicate(dup)
+	}
+	switch policy {
+	case DuplicateLastWins, DuplicateFirstWins:
+		return policy, nil
+	case DuplicateError:
+		return 0, dup
+	default:
+		return 0, fmt.Errorf("invalid duplicate policy %d", policy)
+	}
+}
@@ -0,0 +1,104 @@
+package jsontree
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDuplicatePolicy(t *testing.T) {
+	// The first value contains a newline to test line numbers
+	in := "{\"root\":{\"a\":\"v1\n\",\"b\":{\"x\":\"v2\"},\"a\":\"v3\",\"b\":{\"y\":\"v4\"}}}"
+	tests := []struct {
+		name   string
+		policy DuplicatePolicy
+		want   *testNode
+		err    error
+	}{
+		{
+			name:   "last wins",
+			policy: DuplicateLastWins,
+			want: &testNode{key: key("root"), nodes: []*testNode{
+				{key: key("a"), value: val("v3")},
+				{key: key("b"), nodes: []*testNode{
+					{key: key("x"), value: val("v2")},
+					{key: key("y"), value: val("v4")},
+				}},
+			}},
+		},
+		{
+			name:   "first wins",
+			policy: DuplicateFirstWins,
+			want: &testNode{key: key("root"), nodes: []*testNode{
+				{key: key("a"), value: val("v1\n")},
+				{key: key("b"), nodes: []*testNode{
+					{key: key("x"), value: val("v2")},
+				}},
+			}},
+		},
+		{
+			name:   "error",
+			policy: DuplicateError,
+			err: &DuplicateKeyError{
+				Path:   [][]byte{key("root"), key("a")},
+				First:  Position{Offset: 9, Line: 1, Column: 10},
+				Second: Position{Offset: 34, Line: 2, Column: 18},
+			},
+		},
+	}
+	for _, test := range tests {
+		opts := DecodeOptions{Duplicates: test.policy}
+		for _, fromBytes := range []bool{false, true} {
+			node := new(testNode)
+			var err error
+			if fromBytes {
+				err = opts.DeserializeBytes(node, []byte(in))
+			} else {
+				err = opts.DeserializeNode(node, strings.NewReader(in))
+			}
+			if !errEqual(test.err, err) {
+				t.Errorf("%s (bytes: %v): Wrong error\nWant %v\nGot  %v", test.name, fromBytes, test.err, err)
+				continue
+			}
+			if test.err == nil && !nodeEqual(node, test.want) {
+				t.Errorf("%s (bytes: %v): Node was not as expected\nWant %v\nGot  %v", test.name, fromBytes, nodeString(test.want), nodeString(node))
+			}
+		}
+	}
+}
+
+func TestDuplicateCallback(t *testing.T) {
+	in := `{"root":{"a":"v1","b":"v2","a":"v3","b":"v4"}}`
+	var dups []string
+	opts := DecodeOptions{
+		Duplicates: DuplicateCallback,
+		OnDuplicate: func(err *DuplicateKeyError) DuplicatePolicy {
+			dups = append(dups, fmt.Sprintf("%s@%d", err.Path[1], err.Second.Offset))
+			if string(err.Path[1]) == "a" {
+				return DuplicateFirstWins
+			}
+			return DuplicateLastWins
+		},
+	}
+	node := new(testNode)
+	if err := opts.DeserializeNode(node, strings.NewReader(in)); err != nil {
+		t.Fatalf("DeserializeNode() error: %v", err)
+	}
+	want := &testNode{key: key("root"), nodes: []*testNode{
+		{key: key("a"), value: val("v1")},
+		{key: key("b"), value: val("v4")},
+	}}
+	if !nodeEqual(node, want) {
+		t.Errorf("Node was not as expected\nWant %v\nGot  %v", nodeString(want), nodeString(node))
+	}
+	if got := strings.Join(dups, ","); got != "a@27,b@36" {
+		t.Errorf("OnDuplicate called with %s, want a@27,b@36", got)
+	}
+
+	// OnDuplicate must be set
+	opts.OnDuplicate = nil
+	want2 := fmt.Errorf("DecodeOptions.OnDuplicate must be set when using DuplicateCallback")
+	if err := opts.DeserializeNode(new(testNode), strings.NewReader(in)); !errEqual(want2, err) {
+		t.Errorf("Wrong error\nWant %v\nGot  %v", want2, err)
+	}
+}
@@ -0,0 +1,135 @@
+package jsontree
+
+import (
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Keys and values hold the contents of JSON strings as they appear in the
+// document, escape sequences included. unescapeString and appendEscaped
+// convert between that form and plain UTF-8.
+
+// unescapeString returns the UTF-8 text of the raw contents of a JSON string
+func unescapeString(raw []byte) ([]byte, error) {
+	i := 0
+	for i < len(raw) && raw[i] != '\\' {
+		i++
+	}
+	if i == len(raw) {
+		return raw, nil // nothing to unescape
+	}
+	bs := make([]byte, i, len(raw))
+	copy(bs, raw[:i])
+	for i < len(raw) {
+		b := raw[i]
+		if b != '\\' {
+			bs = append(bs, b)
+			i++
+			continue
+		}
+		if i+1 >= len(raw) {
+			return nil, fmt.Errorf("invalid escape at end of string")
+		}
+		esc := raw[i+1]
+		i += 2
+		switch esc {
+		case '"', '\\', '/':
+			bs = append(bs, esc)
+		case 'b':
+			bs = append(bs, '\b')
+		case 'f':
+			bs = append(bs, '\f')
+		case 'n':
+			bs = append(bs, '\n')
+		case 'r':
+			bs = append(bs, '\r')
+		case 't':
+			bs = append(bs, '\t')
+		case 'u':
+			r, n, err := readUnicodeEscape(raw[i:])
+			if err != nil {
+				return nil, err
+			}
+			i += n
+			bs = utf8.AppendRune(bs, r)
+		default:
+			return nil, fmt.Errorf("invalid escape '\\%s'", string(esc))
+		}
+	}
+	return bs, nil
+}
+
+// readUnicodeEscape reads the hex digits of a \u escape, and of the low
+// surrogate following it if it is a high surrogate.
+func readUnicodeEscape(bs []byte) (r rune, n int, err error) {
+	r, err = readHex4(bs)
+	if err != nil {
+		return 0, 0, err
+	}
+	n = 4
+	if utf16.IsSurrogate(r) {
+		if r >= 0xdc00 || len(bs) < 10 || bs[4] != '\\' || bs[5] != 'u' {
+			return 0, 0, fmt.Errorf("invalid surrogate \\u%s", string(bs[:4]))
+		}
+		low, err := readHex4(bs[6:])
+		if err != nil {
+			return 0, 0, err
+		}
+		if r = utf16.DecodeRune(r, low); r == utf8.RuneError {
+			return 0, 0, fmt.Errorf("invalid surrogate pair \\u%s", string(bs[:10]))
+		}
+		n = 10
+	}
+	return r, n, nil
+}
+
+func readHex4(bs []byte) (rune, error) {
+	if len(bs) < 4 {
+		return 0, fmt.Errorf("invalid unicode escape")
+	}
+	var r rune
+	for _, b := range bs[:4] {
+		r <<= 4
+		switch {
+		case b >= '0' && b <= '9':
+			r |= rune(b - '0')
+		case b >= 'a' && b <= 'f':
+			r |= rune(b - 'a' + 10)
+		case b >= 'A' && b <= 'F':
+			r |= rune(b - 'A' + 10)
+		default:
+			return 0, fmt.Errorf("invalid unicode escape \\u%s", string(bs[:4]))
+		}
+	}
+	return r, nil
+}
+
+// appendEscaped appends s to dst, escaped as the contents of a JSON string.
+// Only what has to be escaped is: quotes, backslashes and control
+// characters, using the short forms where there are any. This is the
+// escaping of RFC 8785.
+func appendEscaped(dst, s []byte) []byte {
+	const hex = "0123456789abcdef"
+	for _, b := range s {
+		switch {
+		case b == '"' || b == '\\':
+			dst = append(dst, '\\', b)
+		case b == '\b':
+			dst = append(dst, '\\', 'b')
+		case b == '\f':
+			dst = append(dst, '\\', 'f')
+		case b == '\n':
+			dst = append(dst, '\\', 'n')
+		case b == '\r':
+			dst = append(dst, '\\', 'r')
+		case b == '\t':
+			dst = append(dst, '\\', 't')
+		case b < 0x20:
+			dst = append(dst, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xf])
+		default:
+			dst = append(dst, b)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,61 @@
+package jsontree
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestUnescapeString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  error
+	}{
+		{`plain`, "plain", nil},
+		{``, "", nil},
+		{`a\"b\\c\/d`, `a"b\c/d`, nil},
+		{`\b\f\n\r\t`, "\b\f\n\r\t", nil},
+		{`\u00e9\u20AC`, "é€", nil},
+		{`\ud83d\ude00`, "😀", nil},
+		{`é`, "é", nil},
+		{`\x`, "", fmt.Errorf("invalid escape '\\x'")},
+		{`a\`, "", fmt.Errorf("invalid escape at end of string")},
+		{`\u12`, "", fmt.Errorf("invalid unicode escape")},
+		{`\u12zz`, "", fmt.Errorf("invalid unicode escape \\u12zz")},
+		{`\ud83d`, "", fmt.Errorf("invalid surrogate \\ud83d")},
+		{`\ude00\ud83d`, "", fmt.Errorf("invalid surrogate \\ude00")},
+		{`\ud83d\u0041`, "", fmt.Errorf("invalid surrogate pair \\ud83d\\u0041")},
+	}
+	for _, test := range tests {
+		got, err := unescapeString([]byte(test.in))
+		if test.err != nil {
+			if !errEqual(test.err, err) {
+				t.Errorf("unescapeString(%s): Wrong error\nWant %v\nGot  %v", test.in, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unescapeString(%s): Unexpected error: %v", test.in, err)
+		} else if string(got) != test.want {
+			t.Errorf("unescapeString(%s) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestAppendEscaped(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{`a"b\c/d`, `a\"b\\c/d`},
+		{"\b\f\n\r\t", `\b\f\n\r\t`},
+		{"\x00\x1f\x7f", `\u0000\u001f` + "\x7f"},
+		{"é€😀 ", "é€😀 "},
+	}
+	for _, test := range tests {
+		if got := string(appendEscaped([]byte("x"), []byte(test.in))); got != "x"+test.want {
+			t.Errorf("appendEscaped(%q) = %s, want x%s", test.in, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package jsontree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// Hash writes the canonical serialization of node to h. The resulting digest
+// is the same for any two trees that are equal apart from the order of their
+// nodes and the escaping of their keys and values.
+func Hash(node Node, h hash.Hash) error {
+	return EncodeOptions{Canonical: true}.SerializeNode(node, h)
+}
+
+// HashTree is the Merkle tree of a Node tree. Sum is the digest of the
+// contents of the node: its value, or the keys and digests of its children.
+// The key of the node itself is not part of Sum, so equal subtrees have
+// equal digests wherever they are found. Nodes are sorted like in the
+// canonical serialization.
+type HashTree struct {
+	Key   []byte // the canonical key of the node
+	Sum   []byte
+	Nodes []*HashTree
+}
+
+// Domain separation of leaf and parent digests, so that a leaf can never have
+// the digest of a parent
+const (
+	hashLeaf   byte = 0
+	hashParent byte = 1
+)
+
+// MerkleHash returns the Merkle tree of node, using newHash to create the hash
+// of each node.
+func MerkleHash(node Node, newHash func() hash.Hash) (*HashTree, error) {
+	if node == nil {
+		return nil, fmt.Errorf("node is nil")
+	}
+	key, err := canonicalString(node.Key())
+	if err != nil {
+		return nil, err
+	}
+	tree := &HashTree{Key: key}
+	h := newHash()
+	if nodes := node.Nodes(); len(nodes) > 0 {
+		if nodes, err = sortCanonical(nodes); err != nil {
+			return nil, err
+		}
+		h.Write([]byte{hashParent})
+		tree.Nodes = make([]*HashTree, len(nodes))
+		for i, child := range nodes {
+			if tree.Nodes[i], err = MerkleHash(child, newHash); err != nil {
+				return nil, err
+			}
+			writeHashField(h, tree.Nodes[i].Key)
+			writeHashField(h, tree.Nodes[i].Sum)
+		}
+	} else if value := node.Value(); value != nil {
+		b, err := value.Serialize()
+		if err != nil {
+			return nil, err
+		}
+		if b, err = canonicalString(b); err != nil {
+			return nil, err
+		}
+		h.Write([]byte{hashLeaf})
+		writeHashField(h, b)
+	} else {
+		return nil, fmt.Errorf("invalid node: len(node.Nodes()) == 0 and node.Value() == nil")
+	}
+	tree.Sum = h.Sum(nil)
+	return tree, nil
+}
+
+// writeHashField writes b to w, prefixed with its length
+func writeHashField(w io.Writer, b []byte) {
+	var n [binary.MaxVarintLen64]byte
+	w.Write(n[:binary.PutUvarint(n[:], uint64(len(b)))])
+	w.Write(b)
+}
+
+// Get returns the subtree at path, or nil if there is none
+func (tree *HashTree) Get(path ...[]byte) *HashTree {
+	for _, key := range path {
+		var child *HashTree
+		for _, n := range tree.Nodes {
+			if keyEqual(n.Key, key) {
+				child = n
+				break
+			}
+		}
+		if child == nil {
+			return nil
+		}
+		tree = child
+	}
+	return tree
+}
+
+// Diff returns the paths of the subtrees that differ between a and b: those
+// whose digests are not equal, or that are only found in one of the trees.
+// Identical subtrees are skipped without being visited. A changed subtree is
+// reported as the paths of its changed descendants, down to leaves and
+// subtrees found in only one of the trees.
+func Diff(a, b *HashTree) [][][]byte {
+	var paths [][][]byte
+	diffHashTrees(a, b, nil, &paths)
+	return paths
+}
+
+func diffHashTrees(a, b *HashTree, path [][]byte, paths *[][][]byte) {
+	if bytes.Equal(a.Sum, b.Sum) {
+		return
+	}
+	if len(a.Nodes) == 0 || len(b.Nodes) == 0 {
+		*paths = append(*paths, copyPath(path))
+		return
+	}
+	// Both lists are sorted the same way, so they can be merged
+	i, j := 0, 0
+	for i < len(a.Nodes) || j < len(b.Nodes) {
+		var cmp int
+		switch {
+		case i == len(a.Nodes):
+			cmp = 1
+		case j == len(b.Nodes):
+			cmp = -1
+		default:
+			cmp = compareUTF16Keys(a.Nodes[i].Key, b.Nodes[j].Key)
+		}
+		switch {
+		case cmp < 0:
+			*paths = append(*paths, copyPath(append(path, a.Nodes[i].Key)))
+			i++
+		case cmp > 0:
+			*paths = append(*paths, copyPath(append(path, b.Nodes[j].Key)))
+			j++
+		default:
+			diffHashTrees(a.Nodes[i], b.Nodes[j], append(path, a.Nodes[i].Key), paths)
+			i++
+			j++
+		}
+	}
+}
+
+func copyPath(path [][]byte) [][]byte {
+	return append([][]byte(nil), path...)
+}
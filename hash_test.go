@@ -0,0 +1,95 @@
+package jsontree
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	a := &testNode{key: key("root"), nodes: []*testNode{
+		{key: key("a"), value: val("1")},
+		{key: key("b"), value: val(`é`)},
+	}}
+	b := &testNode{key: key("root"), nodes: []*testNode{
+		{key: key("b"), value: val("é")},
+		{key: key("a"), value: val("1")},
+	}}
+	c := &testNode{key: key("root"), nodes: []*testNode{
+		{key: key("a"), value: val("2")},
+		{key: key("b"), value: val("é")},
+	}}
+	sum := func(node Node) []byte {
+		h := sha256.New()
+		if err := Hash(node, h); err != nil {
+			t.Fatalf("Hash() error: %v", err)
+		}
+		return h.Sum(nil)
+	}
+	if !bytes.Equal(sum(a), sum(b)) {
+		t.Errorf("Hash() of equivalent trees differ")
+	}
+	if bytes.Equal(sum(a), sum(c)) {
+		t.Errorf("Hash() of different trees are equal")
+	}
+}
+
+func TestMerkleHash(t *testing.T) {
+	before := &testNode{key: key("root"), nodes: []*testNode{
+		{key: key("db"), nodes: []*testNode{
+			{key: key("host"), value: val("localhost")},
+			{key: key("port"), value: val("5432")},
+		}},
+		{key: key("cache"), nodes: []*testNode{
+			{key: key("ttl"), value: val("60")},
+		}},
+		{key: key("old"), value: val("x")},
+	}}
+	after := &testNode{key: key("root"), nodes: []*testNode{
+		{key: key("cache"), nodes: []*testNode{
+			{key: key("ttl"), value: val("60")},
+		}},
+		{key: key("db"), nodes: []*testNode{
+			{key: key("port"), value: val("5433")},
+			{key: key("host"), value: val("localhost")},
+		}},
+		{key: key("new"), nodes: []*testNode{
+			{key: key("a"), value: val("1")},
+		}},
+	}}
+	a, err := MerkleHash(before, sha256.New)
+	if err != nil {
+		t.Fatalf("MerkleHash() error: %v", err)
+	}
+	b, err := MerkleHash(after, sha256.New)
+	if err != nil {
+		t.Fatalf("MerkleHash() error: %v", err)
+	}
+	if bytes.Equal(a.Sum, b.Sum) {
+		t.Errorf("Root digests of different trees are equal")
+	}
+	if !bytes.Equal(a.Get(key("cache")).Sum, b.Get(key("cache")).Sum) {
+		t.Errorf("Digests of equal subtrees differ")
+	}
+	if !bytes.Equal(a.Get(key("db"), key("host")).Sum, b.Get(key("db"), key("host")).Sum) {
+		t.Errorf("Digests of equal leaves differ")
+	}
+	if a.Get(key("db"), key("nope")) != nil {
+		t.Errorf("Get() of missing path returned a subtree")
+	}
+	var got []string
+	for _, path := range Diff(a, b) {
+		got = append(got, string(bytes.Join(path, []byte{'.'})))
+	}
+	if want := "db.port,new,old"; strings.Join(got, ",") != want {
+		t.Errorf("Diff() = %s, want %s", strings.Join(got, ","), want)
+	}
+
+	// Errors
+	wantErr := fmt.Errorf("Test err")
+	if _, err := MerkleHash(&testNode{key: key("k"), value: valErr("", wantErr, nil)}, sha256.New); !errEqual(wantErr, err) {
+		t.Errorf("MerkleHash() returned wrong error\nWant %v\nGot  %v", wantErr, err)
+	}
+}
@@ -0,0 +1,152 @@
+package jsontree
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// InferSchema returns a schema that the nodes are valid against, describing
+// the union of their keys. Like with Schema.Validate, a node is an object if
+// it has children or no key. A key is required if every object it could be in
+// has it. The type of leaves is the most specific of boolean, integer, number
+// and string that fits every value, and strings that all have a common format,
+// like dates or UUIDs, get its pattern.
+func InferSchema(nodes ...Node) (*Schema, error) {
+	in := new(inference)
+	for _, node := range nodes {
+		if node == nil {
+			return nil, fmt.Errorf("node is nil")
+		}
+		if err := in.add(node, len(node.Nodes()) > 0 || node.Key() == nil); err != nil {
+			return nil, err
+		}
+	}
+	return in.schema(), nil
+}
+
+// inference gathers the nodes found at a path of the trees
+type inference struct {
+	objects int // how many of the nodes are objects
+	texts   []string
+	names   []string // the keys of the properties, in the order found
+	props   map[string]*inference
+	counts  map[string]int // how many objects have each property
+}
+
+func (in *inference) add(node Node, isObject bool) error {
+	if !isObject {
+		text, err := leafText(node)
+		if err != nil {
+			return err
+		}
+		in.texts = append(in.texts, text)
+		return nil
+	}
+	in.objects++
+	if in.props == nil {
+		in.props, in.counts = make(map[string]*inference), make(map[string]int)
+	}
+	seen := make(map[string]bool)
+	for _, child := range node.Nodes() {
+		if child == nil {
+			return fmt.Errorf("invalid node: node.Nodes() contained nil")
+		}
+		key, err := unescapeString(child.Key())
+		if err != nil {
+			return err
+		}
+		name := string(key)
+		prop, ok := in.props[name]
+		if !ok {
+			prop = new(inference)
+			in.props[name] = prop
+			in.names = append(in.names, name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			in.counts[name]++
+		}
+		if err := prop.add(child, len(child.Nodes()) > 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (in *inference) schema() *Schema {
+	s := new(Schema)
+	if in.objects > 0 {
+		s.Type = append(s.Type, "object")
+		for _, name := range in.names {
+			if s.Properties == nil {
+				s.Properties = make(map[string]*Schema)
+			}
+			s.Properties[name] = in.props[name].schema()
+			if in.counts[name] == in.objects {
+				s.Required = append(s.Required, name)
+			}
+		}
+	}
+	if len(in.texts) > 0 {
+		typ := leafType(in.texts)
+		s.Type = append(s.Type, typ)
+		if typ == "string" {
+			s.Pattern = commonPattern(in.texts)
+		}
+	}
+	return s
+}
+
+// leafType returns the most specific type of all texts
+func leafType(texts []string) string {
+	for _, typ := range []string{"boolean", "integer", "number"} {
+		all := true
+		for _, text := range texts {
+			if !hasLeafType([]string{typ}, text) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return typ
+		}
+	}
+	return "string"
+}
+
+// stringPatterns are the formats InferSchema recognizes, most specific first
+var stringPatterns = []string{
+	`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`,
+	`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`,
+	`^[0-9]{4}-[0-9]{2}-[0-9]{2}[Tt ][0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?([Zz]|[+-][0-9]{2}:[0-9]{2})$`,
+	`^v?[0-9]+\.[0-9]+\.[0-9]+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`,
+	`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`,
+	`^[a-z][a-z0-9+.-]*://[^\s]+$`,
+	`^[^@\s]+@[^@\s]+\.[^@\s]+$`,
+}
+
+var stringPatternRegexps = func() []*regexp.Regexp {
+	res := make([]*regexp.Regexp, len(stringPatterns))
+	for i, pattern := range stringPatterns {
+		res[i] = regexp.MustCompile(pattern)
+	}
+	return res
+}()
+
+// commonPattern returns the first of stringPatterns that all texts match, or
+// ""
+func commonPattern(texts []string) string {
+	for i, re := range stringPatternRegexps {
+		all := true
+		for _, text := range texts {
+			if !re.MatchString(text) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return stringPatterns[i]
+		}
+	}
+	return ""
+}
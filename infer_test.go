@@ -0,0 +1,81 @@
+package jsontree
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestInferSchema(t *testing.T) {
+	var nodes []Node
+	for _, doc := range []string{
+		`{"name":"web","port":"8080","debug":"true","id":"123e4567-e89b-12d3-a456-426614174000","timeout":"1m30s","tls":{"cert":"a.pem"},"ratio":"1"}`,
+		`{"name":"db","port":"5432","debug":"false","id":"00000000-0000-0000-0000-000000000000","timeout":"5s","tls":"off","ratio":"0.5","url":"postgres://db"}`,
+	} {
+		node := &testNode{}
+		if err := (DecodeOptions{MultipleRoots: true}).DeserializeNode(node, strings.NewReader(doc)); err != nil {
+			t.Fatalf("DeserializeNode() error: %v", err)
+		}
+		nodes = append(nodes, node)
+	}
+	schema, err := InferSchema(nodes...)
+	if err != nil {
+		t.Fatalf("InferSchema() error: %v", err)
+	}
+	got, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("json.Marshal() error: %v", err)
+	}
+	want := `{"type":"object","required":["name","port","debug","id","timeout","tls","ratio"],"properties":{` +
+		`"debug":{"type":"boolean"},` +
+		`"id":{"type":"string","pattern":"^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$"},` +
+		`"name":{"type":"string"},` +
+		`"port":{"type":"integer"},` +
+		`"ratio":{"type":"number"},` +
+		`"timeout":{"type":"string","pattern":"^([0-9]+(\\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$"},` +
+		`"tls":{"type":["object","string"],"required":["cert"],"properties":{"cert":{"type":"string"}}},` +
+		`"url":{"type":"string","pattern":"^[a-z][a-z0-9+.-]*://[^\\s]+$"}}}`
+	if string(got) != want {
+		t.Errorf("Wrong schema\nWant %s\nGot  %s", want, got)
+	}
+	// The nodes are valid against the schema, once written and read back
+	parsed, err := ParseSchema(got)
+	if err != nil {
+		t.Fatalf("ParseSchema() error: %v", err)
+	}
+	for _, node := range nodes {
+		if err := parsed.Validate(node); err != nil {
+			t.Errorf("Validate() error: %v", err)
+		}
+	}
+
+	if _, err := InferSchema(&testNode{nilNodes: true}); err == nil {
+		t.Errorf("InferSchema() returned no error for an invalid node")
+	}
+	if schema, err := InferSchema(); err != nil || len(schema.Type) > 0 {
+		t.Errorf("InferSchema() = %+v, %v", schema, err)
+	}
+	if got, _ := json.Marshal(&Schema{AdditionalProperties: &Schema{False: true}}); string(got) != `{"additionalProperties":false}` {
+		t.Errorf("Wrong schema %s", got)
+	}
+}
+
+func TestInferSchemaDecode(t *testing.T) {
+	sample := &testNode{}
+	if err := DeserializeNode(sample, strings.NewReader(`{"root":{"a":"1","b":"x"}}`)); err != nil {
+		t.Fatalf("DeserializeNode() error: %v", err)
+	}
+	schema, err := InferSchema(sample)
+	if err != nil {
+		t.Fatalf("InferSchema() error: %v", err)
+	}
+	opts := DecodeOptions{Schema: schema}
+	if err := opts.DeserializeNode(&testNode{}, strings.NewReader(`{"root":{"a":"2","b":"y"}}`)); err != nil {
+		t.Errorf("DeserializeNode() error: %v", err)
+	}
+	err = opts.DeserializeNode(&testNode{}, strings.NewReader(`{"root":{"a":"z"}}`))
+	want := "missing required property \"b\" (at \"\")\nexpected integer, got string (at \"/a\")"
+	if err == nil || err.Error() != want {
+		t.Errorf("Wrong error\nWant %s\nGot  %v", want, err)
+	}
+}
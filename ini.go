@@ -0,0 +1,270 @@
+package jsontree
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// INI sections are the first level of nodes, and the keys before the first
+// section leaves at that level. Values that would not read back as they are,
+// like values with leading spaces or line breaks, are double quoted. The
+// escapes of quoted values are \\, \", \n and \t; other control characters
+// are written as they are. Comments are kept by CommentNodes, like in
+// .properties files.
+
+func DeserializeINI(node Node, r io.Reader) error {
+	return DecodeOptions{}.DeserializeINI(node, r)
+}
+
+func SerializeINI(node Node, w io.Writer) error {
+	return EncodeOptions{}.SerializeINI(node, w)
+}
+
+// DeserializeINI reads an INI file into node. Like DeserializeNode, the file
+// must have a single section and no keys outside of it unless
+// opts.MultipleRoots is set. Keys found more than once in a section are
+// handled by opts.Duplicates. Sections without keys are left out, as a node
+// can not be an empty object. MaxBytes is the only limit that applies.
+func (opts DecodeOptions) DeserializeINI(node Node, r io.Reader) error {
+	data, err := readAll(r, opts.MaxBytes)
+	if err != nil {
+		return err
+	}
+	tree := &treeBuilder{root: node, multi: opts.MultipleRoots}
+	lines, offsets := splitLines(data)
+	var section, comment, sectionComment []byte
+	for i, line := range lines {
+		pos := Position{Offset: offsets[i], Line: i + 1, Column: 1}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			comment = nil
+			continue
+		}
+		switch line[0] {
+		case ';', '#':
+			comment = appendCommentLine(comment, line[1:])
+			continue
+		case '[':
+			if line[len(line)-1] != ']' {
+				return &SyntaxError{Pos: pos, Err: fmt.Errorf("expected ']'")}
+			}
+			name := bytes.TrimSpace(line[1 : len(line)-1])
+			if len(name) == 0 {
+				return &SyntaxError{Pos: pos, Err: fmt.Errorf("empty section name")}
+			}
+			// The node of the section is added with its first key
+			section, sectionComment, comment = appendEscaped(nil, name), comment, nil
+			continue
+		}
+		sep := bytes.IndexAny(line, "=:")
+		if sep <= 0 {
+			return &SyntaxError{Pos: pos, Err: fmt.Errorf("expected 'key = value'")}
+		}
+		key, value := bytes.TrimSpace(line[:sep]), bytes.TrimSpace(line[sep+1:])
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			if value, err = unescapeINI(value[1 : len(value)-1]); err != nil {
+				return &SyntaxError{Pos: pos, Err: err}
+			}
+		}
+		path := [][]byte{appendEscaped(nil, key)}
+		if section != nil {
+			path = [][]byte{section, path[0]}
+			if sectionComment != nil {
+				if n, err := tree.node(path[:1]); err == nil {
+					if cn, ok := n.(CommentNode); ok {
+						cn.SetComment(sectionComment)
+					}
+				}
+				sectionComment = nil
+			}
+		}
+		if skip, err := tree.duplicate(path, pos, &opts); err != nil || skip {
+			if err != nil {
+				return err
+			}
+			comment = nil
+			continue
+		}
+		n, err := tree.leaf(path)
+		if err != nil {
+			return &SyntaxError{Path: path, Pos: pos, Err: err}
+		}
+		if err := n.Value().Deserialize(appendEscaped(nil, value)); err != nil {
+			return err
+		}
+		if cn, ok := n.(CommentNode); ok && comment != nil {
+			cn.SetComment(comment)
+		}
+		comment = nil
+	}
+	return nil
+}
+
+// SerializeINI writes node as an INI file. Only trees of sections and keys
+// can be written: nodes deeper than that are an error.
+func (opts EncodeOptions) SerializeINI(node Node, w io.Writer) error {
+	if node == nil {
+		return fmt.Errorf("node is nil")
+	}
+	nodes := []Node{node}
+	if opts.MultipleRoots {
+		nodes = node.Nodes()
+	}
+	nodes, err := opts.order(nodes)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	var sections []Node
+	for _, node := range nodes {
+		if node == nil {
+			return fmt.Errorf("invalid node: node.Nodes() contained nil")
+		}
+		if len(node.Nodes()) > 0 {
+			sections = append(sections, node)
+		} else if err := writeINIKey(bw, node); err != nil {
+			return err
+		}
+	}
+	for i, section := range sections {
+		if i > 0 || len(sections) < len(nodes) {
+			bw.WriteByte('\n')
+		}
+		name, err := unescapeString(section.Key())
+		if err != nil {
+			return err
+		}
+		if !isINIKey(name) || bytes.IndexByte(name, ']') >= 0 {
+			return fmt.Errorf("section \"%s\" can not be written to an INI file", section.Key())
+		}
+		if cn, ok := section.(CommentNode); ok && len(cn.Comment()) > 0 {
+			writeCommentLines(bw, ";", cn.Comment())
+		}
+		bw.WriteByte('[')
+		bw.Write(name)
+		bw.WriteString("]\n")
+		children, err := opts.order(section.Nodes())
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			if child == nil {
+				return fmt.Errorf("invalid node: node.Nodes() contained nil")
+			}
+			if len(child.Nodes()) > 0 {
+				return fmt.Errorf("node \"%s\" is too deep to be written to an INI file", child.Key())
+			}
+			if err := writeINIKey(bw, child); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+func writeINIKey(w *bufio.Writer, node Node) error {
+	key, err := unescapeString(node.Key())
+	if err != nil {
+		return err
+	}
+	if !isINIKey(key) || bytes.IndexAny(key, "=:") >= 0 {
+		return fmt.Errorf("key \"%s\" can not be written to an INI file", node.Key())
+	}
+	value := node.Value()
+	if value == nil {
+		return fmt.Errorf("invalid node: len(node.Nodes()) == 0 and node.Value() == nil")
+	}
+	raw, err := value.Serialize()
+	if err != nil {
+		return err
+	}
+	text, err := unescapeString(raw)
+	if err != nil {
+		return err
+	}
+	if cn, ok := node.(CommentNode); ok && len(cn.Comment()) > 0 {
+		writeCommentLines(w, ";", cn.Comment())
+	}
+	w.Write(key)
+	w.WriteString(" = ")
+	if needsINIQuotes(text) {
+		w.Write(appendINIQuoted(nil, text))
+	} else {
+		w.Write(text)
+	}
+	return w.WriteByte('\n')
+}
+
+// isINIKey reports whether text can be written as a key or a section name
+func isINIKey(text []byte) bool {
+	if len(text) == 0 || !bytes.Equal(text, bytes.TrimSpace(text)) || bytes.ContainsAny(text, "\r\n") {
+		return false
+	}
+	return text[0] != '[' && text[0] != ';' && text[0] != '#'
+}
+
+// needsINIQuotes reports whether text would not read back as it is
+func needsINIQuotes(text []byte) bool {
+	if len(text) == 0 {
+		return false
+	}
+	if text[0] == '"' || !bytes.Equal(text, bytes.TrimSpace(text)) {
+		return true
+	}
+	for _, b := range text {
+		if b < 0x20 || b == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// unescapeINI returns the text of the contents of a quoted value
+func unescapeINI(raw []byte) ([]byte, error) {
+	if bytes.IndexByte(raw, '\\') < 0 {
+		return raw, nil
+	}
+	text := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' {
+			text = append(text, raw[i])
+			continue
+		}
+		if i+1 == len(raw) {
+			return nil, fmt.Errorf("invalid escape at end of string")
+		}
+		i++
+		switch esc := raw[i]; esc {
+		case '\\', '"':
+			text = append(text, esc)
+		case 'n':
+			text = append(text, '\n')
+		case 't':
+			text = append(text, '\t')
+		default:
+			return nil, fmt.Errorf("invalid escape '\\%c'", esc)
+		}
+	}
+	return text, nil
+}
+
+// appendINIQuoted appends text to dst as a quoted value. Control characters
+// without an escape are written as they are.
+func appendINIQuoted(dst, text []byte) []byte {
+	dst = append(dst, '"')
+	for _, b := range text {
+		switch b {
+		case '\\', '"':
+			dst = append(dst, '\\', b)
+		case '\n':
+			dst = append(dst, '\\', 'n')
+		case '\t':
+			dst = append(dst, '\\', 't')
+		default:
+			dst = append(dst, b)
+		}
+	}
+	return append(dst, '"')
+}
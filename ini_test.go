@@ -0,0 +1,187 @@
+package jsontree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDeserializeINI(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		multi bool
+		dups  DuplicatePolicy
+		want  string
+		err   string
+	}{
+		{
+			name: "section",
+			in:   "; translations\n[en]\ngreeting = Hello, world\n  menu: Open file\nempty =\n",
+			want: `{"en":{"greeting":"Hello, world","menu":"Open file","empty":""}}`,
+		},
+		{
+			name: "quoted values",
+			in:   "[en]\na = \" x \"\nb = \"line 1\\nline 2\"\nc = say \"hi\"\n",
+			want: `{"en":{"a":" x ","b":"line 1\nline 2","c":"say \"hi\""}}`,
+		},
+		{
+			name:  "global keys and sections",
+			in:    "a = x\n\n[ b ]\nc = y\n[d]\ne = z\n",
+			multi: true,
+			want:  `{"a":"x","b":{"c":"y"},"d":{"e":"z"}}`,
+		},
+		{
+			name: "several sections",
+			in:   "[en]\na = x\n[fr]\na = y\n",
+			err:  `4:1: expected 1 root node (at "fr.a")`,
+		},
+		{
+			name: "duplicate key, last wins",
+			in:   "[en]\na = x\nb = y\na = z\n",
+			want: `{"en":{"a":"z","b":"y"}}`,
+		},
+		{
+			name: "duplicate key, first wins",
+			in:   "[en]\na = x\nb = y\na = z\n",
+			dups: DuplicateFirstWins,
+			want: `{"en":{"a":"x","b":"y"}}`,
+		},
+		{
+			name: "duplicate key",
+			in:   "[en]\na = x\nb = y\na = z\n",
+			dups: DuplicateError,
+			err:  `duplicate key "en.a" at 4:1, first defined at 2:1`,
+		},
+		{
+			name:  "section without keys",
+			in:    "[a]\nx = 1\n[e]\n",
+			multi: true,
+			want:  `{"a":{"x":"1"}}`,
+		},
+		{
+			name: "unterminated section",
+			in:   "[en\n",
+			err:  `1:1: expected ']' (at "")`,
+		},
+		{
+			name: "escape of another format",
+			in:   "[en]\na = \"\\x41\"\n",
+			err:  `2:1: invalid escape '\x' (at "")`,
+		},
+		{
+			name: "missing separator",
+			in:   "[en]\nkey\n",
+			err:  `2:1: expected 'key = value' (at "")`,
+		},
+	}
+	for _, test := range tests {
+		node := &testNode{}
+		err := DecodeOptions{MultipleRoots: test.multi, Duplicates: test.dups}.DeserializeINI(node, strings.NewReader(test.in))
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: Wrong error\nWant %s\nGot  %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %v", test.name, err)
+			continue
+		}
+		var buf bytes.Buffer
+		if err := (EncodeOptions{MultipleRoots: test.multi}).SerializeNode(node, &buf); err != nil {
+			t.Errorf("%s: SerializeNode() error: %v", test.name, err)
+		} else if got := buf.String(); got != test.want {
+			t.Errorf("%s: Wrong tree\nWant %s\nGot  %s", test.name, test.want, got)
+		}
+	}
+}
+
+func TestSerializeINI(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  string
+		multi bool
+		want  string
+		err   string
+	}{
+		{
+			name: "section",
+			json: `{"en":{"greeting":"Hello","spaced":" x","quoted":"\"x\"","lines":"a\nb"}}`,
+			want: "[en]\ngreeting = Hello\nspaced = \" x\"\nquoted = \"\\\"x\\\"\"\nlines = \"a\\nb\"\n",
+		},
+		{
+			name: "control characters",
+			json: `{"en":{"a":"x\ty\u0001\\é"}}`,
+			want: "[en]\na = \"x\\ty\x01\\\\é\"\n",
+		},
+		{
+			name:  "global keys and sections",
+			json:  `{"b":{"c":"y"},"a":"x","d":{"e":"z"}}`,
+			multi: true,
+			want:  "a = x\n\n[b]\nc = y\n\n[d]\ne = z\n",
+		},
+		{
+			name: "too deep",
+			json: `{"en":{"a":{"b":"x"}}}`,
+			err:  `node "a" is too deep to be written to an INI file`,
+		},
+		{
+			name: "invalid key",
+			json: `{"en":{"a=b":"x"}}`,
+			err:  `key "a=b" can not be written to an INI file`,
+		},
+	}
+	for _, test := range tests {
+		node := &testNode{}
+		if err := (DecodeOptions{MultipleRoots: test.multi}).DeserializeNode(node, strings.NewReader(test.json)); err != nil {
+			t.Fatalf("%s: DeserializeNode() error: %v", test.name, err)
+		}
+		var buf bytes.Buffer
+		err := (EncodeOptions{MultipleRoots: test.multi}).SerializeINI(node, &buf)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: Wrong error\nWant %s\nGot  %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: SerializeINI() error: %v", test.name, err)
+			continue
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("%s: Wrong INI\nWant %q\nGot  %q", test.name, test.want, got)
+		}
+		back := &testNode{}
+		if err := (DecodeOptions{MultipleRoots: test.multi}).DeserializeINI(back, &buf); err != nil {
+			t.Errorf("%s: DeserializeINI() error: %v", test.name, err)
+			continue
+		}
+		want, got := new(bytes.Buffer), new(bytes.Buffer)
+		opts := EncodeOptions{MultipleRoots: test.multi, Canonical: true}
+		opts.SerializeNode(node, want)
+		opts.SerializeNode(back, got)
+		if got.String() != want.String() {
+			t.Errorf("%s: Round trip changed the tree\nWant %s\nGot  %s", test.name, want, got)
+		}
+	}
+}
+
+func TestINIComments(t *testing.T) {
+	in := "; the English texts\n[en]\n# the greeting\nhello = Hello\n"
+	node := new(DocumentNode)
+	if err := DeserializeINI(node, strings.NewReader(in)); err != nil {
+		t.Fatalf("DeserializeINI() error: %v", err)
+	}
+	if got, want := string(node.Comment()), "the English texts"; got != want {
+		t.Errorf("Wrong section comment\nWant %q\nGot  %q", want, got)
+	}
+	var buf bytes.Buffer
+	if err := SerializeINI(node, &buf); err != nil {
+		t.Fatalf("SerializeINI() error: %v", err)
+	}
+	want := "; the English texts\n[en]\n; the greeting\nhello = Hello\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Wrong INI\nWant %q\nGot  %q", want, got)
+	}
+}
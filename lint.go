@@ -0,0 +1,226 @@
+package jsontree
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LintKind is the kind of problem found by Lint
+type LintKind int
+
+const (
+	// LintMissing is a leaf of the reference missing from the target
+	LintMissing LintKind = iota
+	// LintExtra is a leaf of the target that the reference does not have
+	LintExtra
+	// LintShape is a key that is a leaf in one tree and a parent in the other
+	LintShape
+	// LintPlaceholders is a leaf whose placeholders differ from the reference
+	LintPlaceholders
+	// LintEmpty is an empty leaf, whose reference is not empty
+	LintEmpty
+	// LintUntranslated is a leaf identical to its reference
+	LintUntranslated
+)
+
+var lintKinds = [...]string{"missing", "extra", "shape", "placeholders", "empty", "untranslated"}
+
+func (kind LintKind) String() string {
+	if kind < 0 || int(kind) >= len(lintKinds) {
+		return fmt.Sprintf("LintKind(%d)", int(kind))
+	}
+	return lintKinds[kind]
+}
+
+// LintIssue is a problem found in a target tree. Target is the index of the
+// tree in the targets passed to Lint and Locale its key, unescaped. Path is
+// below the roots of the trees.
+type LintIssue struct {
+	Target  int
+	Locale  string
+	Path    [][]byte
+	Kind    LintKind
+	Message string
+}
+
+func (issue LintIssue) String() string {
+	return fmt.Sprintf("%s: %s: %s (at \"%s\")", issue.Locale, issue.Kind, issue.Message, bytes.Join(issue.Path, []byte{'.'}))
+}
+
+// Lint compares every target tree with the reference tree, and returns the
+// issues found, target by target in the order of the reference. The keys of
+// the roots, usually their locales, are not compared. Placeholders are the
+// arguments of ICU messages, like {name}, and printf verbs, like %s or %1$d.
+// The error is only set for invalid nodes.
+func Lint(reference Node, targets ...Node) ([]LintIssue, error) {
+	if reference == nil {
+		return nil, fmt.Errorf("node is nil")
+	}
+	var issues []LintIssue
+	for i, target := range targets {
+		if target == nil {
+			return nil, fmt.Errorf("node is nil")
+		}
+		locale, err := unescapeString(target.Key())
+		if err != nil {
+			return nil, err
+		}
+		l := &linter{target: i, locale: string(locale)}
+		if err := l.lintNodes(reference, target, nil); err != nil {
+			return nil, err
+		}
+		issues = append(issues, l.issues...)
+	}
+	return issues, nil
+}
+
+type linter struct {
+	target int
+	locale string
+	issues []LintIssue
+}
+
+func (l *linter) add(path [][]byte, kind LintKind, message string) {
+	l.issues = append(l.issues, LintIssue{Target: l.target, Locale: l.locale, Path: path, Kind: kind, Message: message})
+}
+
+// addLeaves adds an issue for every leaf of node, or node itself if it is a
+// leaf
+func (l *linter) addLeaves(node Node, path [][]byte, kind LintKind, message string) error {
+	if len(node.Nodes()) == 0 {
+		l.add(path, kind, message)
+		return nil
+	}
+	paths, err := leafPaths(node)
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		l.add(append(path[:len(path):len(path)], p...), kind, message)
+	}
+	return nil
+}
+
+// lintNodes compares the children of ref and target
+func (l *linter) lintNodes(ref, target Node, path [][]byte) error {
+	for _, t := range target.Nodes() {
+		if t == nil {
+			return fmt.Errorf("invalid node: node.Nodes() contained nil")
+		}
+	}
+	for _, r := range ref.Nodes() {
+		if r == nil {
+			return fmt.Errorf("invalid node: node.Nodes() contained nil")
+		}
+		p := append(path[:len(path):len(path)], r.Key())
+		t := getNode(target, r.Key())
+		var err error
+		switch {
+		case t == nil:
+			err = l.addLeaves(r, p, LintMissing, "key is missing")
+		case len(r.Nodes()) > 0 && len(t.Nodes()) > 0:
+			err = l.lintNodes(r, t, p)
+		case len(r.Nodes()) > 0:
+			l.add(p, LintShape, "key is a value, not a parent as in the reference")
+		case len(t.Nodes()) > 0:
+			l.add(p, LintShape, "key is a parent, not a value as in the reference")
+		default:
+			err = l.lintLeaf(r, t, p)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, t := range target.Nodes() {
+		if getNode(ref, t.Key()) == nil {
+			if err := l.addLeaves(t, append(path[:len(path):len(path)], t.Key()), LintExtra, "key is not in the reference"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l *linter) lintLeaf(ref, target Node, path [][]byte) error {
+	refText, err := leafText(ref)
+	if err != nil {
+		return err
+	}
+	text, err := leafText(target)
+	if err != nil {
+		return err
+	}
+	switch {
+	case strings.TrimSpace(text) == "":
+		if strings.TrimSpace(refText) != "" {
+			l.add(path, LintEmpty, "value is empty")
+		}
+		return nil
+	case text == refText:
+		l.add(path, LintUntranslated, "value is identical to the reference")
+		return nil
+	}
+	refCounts, counts := placeholders(refText), placeholders(text)
+	for _, p := range sortedKeys(refCounts, counts) {
+		switch {
+		case counts[p] < refCounts[p]:
+			l.add(path, LintPlaceholders, fmt.Sprintf("placeholder %s is missing", p))
+		case counts[p] > refCounts[p]:
+			l.add(path, LintPlaceholders, fmt.Sprintf("placeholder %s is not in the reference", p))
+		}
+	}
+	return nil
+}
+
+var (
+	printfVerb     = regexp.MustCompile(`%%|%(?:[0-9]+\$)?[-+#0]*(?:[0-9]+|\*)?(?:\.(?:[0-9]+|\*))?[bcdeEfFgGopqsStTuvxX@]`)
+	icuPlaceholder = regexp.MustCompile(`\{\s*([\pL\pN_-]+)\s*[,}]`)
+)
+
+// placeholders returns how many times the placeholders of text appear in it.
+// The arguments of ICU messages are counted once, wherever they appear.
+func placeholders(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, verb := range printfVerb.FindAllString(text, -1) {
+		if verb != "%%" {
+			counts[verb]++
+		}
+	}
+	if parts, err := parseMessage(text); err == nil {
+		var walk func(parts []messagePart)
+		walk = func(parts []messagePart) {
+			for _, part := range parts {
+				if part.arg != nil {
+					counts["{"+part.arg.name+"}"] = 1
+					for _, c := range part.arg.cases {
+						walk(c.parts)
+					}
+				}
+			}
+		}
+		walk(parts)
+	} else {
+		for _, m := range icuPlaceholder.FindAllStringSubmatch(text, -1) {
+			counts["{"+m[1]+"}"] = 1
+		}
+	}
+	return counts
+}
+
+func sortedKeys(maps ...map[string]int) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
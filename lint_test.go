@@ -0,0 +1,71 @@
+package jsontree
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	var trees []Node
+	for _, doc := range []string{
+		`{"en":{"hello":"Hello, {name}!","count":"{n, plural, one {# file} other {# files}}","size":"%d of %s","ok":"OK","bye":"Bye","menu":{"open":"Open","close":"Close"},"title":"Title"}}`,
+		`{"de":{"hello":"Hallo, {nom}!","count":"{n, plural, one {# Datei} other {# Dateien}}","size":"%s","ok":"OK","bye":" ","menu":"Menü","title":{"a":"b"},"extra":{"x":"1","y":"2"}}}`,
+		`{"fr":{"hello":"Bonjour, {name} !","count":"{count} fichiers","size":"%d sur %s (100%%)","ok":"D'accord","bye":"Au revoir","menu":{"open":"Ouvrir","close":"Fermer"},"title":"Titre"}}`,
+	} {
+		node := &testNode{}
+		if err := DeserializeNode(node, strings.NewReader(doc)); err != nil {
+			t.Fatalf("DeserializeNode() error: %v", err)
+		}
+		trees = append(trees, node)
+	}
+	issues, err := Lint(trees[0], trees[1:]...)
+	if err != nil {
+		t.Fatalf("Lint() error: %v", err)
+	}
+	want := []string{
+		`de: placeholders: placeholder {name} is missing (at "hello")`,
+		`de: placeholders: placeholder {nom} is not in the reference (at "hello")`,
+		`de: placeholders: placeholder %d is missing (at "size")`,
+		`de: untranslated: value is identical to the reference (at "ok")`,
+		`de: empty: value is empty (at "bye")`,
+		`de: shape: key is a value, not a parent as in the reference (at "menu")`,
+		`de: shape: key is a parent, not a value as in the reference (at "title")`,
+		`de: extra: key is not in the reference (at "extra.x")`,
+		`de: extra: key is not in the reference (at "extra.y")`,
+		`fr: placeholders: placeholder {count} is not in the reference (at "count")`,
+		`fr: placeholders: placeholder {n} is missing (at "count")`,
+	}
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Wrong issues\nWant %s\nGot  %s", strings.Join(want, "\n     "), strings.Join(got, "\n     "))
+	}
+	if len(issues) > 0 && (issues[0].Target != 0 || issues[len(issues)-1].Target != 1 || issues[0].Kind != LintPlaceholders) {
+		t.Errorf("Wrong issue fields: %+v", issues[0])
+	}
+
+	missing := &testNode{key: key("it"), nodes: []*testNode{{key: key("ok"), value: val("Va bene")}}}
+	issues, err = Lint(trees[0], missing)
+	if err != nil {
+		t.Fatalf("Lint() error: %v", err)
+	}
+	var paths []string
+	for _, issue := range issues {
+		if issue.Kind != LintMissing {
+			t.Errorf("Wrong kind %s", issue.Kind)
+		}
+		paths = append(paths, string(issue.Path[len(issue.Path)-1]))
+	}
+	if got, want := strings.Join(paths, ","), "hello,count,size,bye,open,close,title"; got != want {
+		t.Errorf("Wrong missing keys\nWant %s\nGot  %s", want, got)
+	}
+
+	if _, err := Lint(trees[0], &testNode{nilNodes: true}); err == nil {
+		t.Errorf("Lint() returned no error for an invalid node")
+	}
+	if got := LintKind(42).String(); got != "LintKind(42)" {
+		t.Errorf("Wrong string %s", got)
+	}
+}
@@ -0,0 +1,510 @@
+package jsontree
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Message is a Value holding an ICU MessageFormat message, like
+// "{count, plural, one {# item} other {# items}}". Deserialize fails if the
+// message is not valid, Serialize returns the string it was passed as is.
+//
+// The argument types are number, date, time, plural, selectordinal and select.
+// Number styles are integer and percent, date and time styles short, medium,
+// long and full; skeletons are not supported.
+type Message struct {
+	raw   []byte
+	parts []messagePart
+}
+
+type messagePart struct {
+	text string
+	arg  *messageArg
+	hash bool // the '#' of a plural case
+}
+
+type messageArg struct {
+	name   string
+	typ    string // empty for a simple argument
+	style  string
+	offset float64
+	cases  []messageCase
+}
+
+type messageCase struct {
+	selector string // a keyword, or '=' and a number
+	parts    []messagePart
+}
+
+// ParseMessage returns the Message of text, which is not escaped
+func ParseMessage(text string) (*Message, error) {
+	m := new(Message)
+	if err := m.Deserialize(appendEscaped(nil, []byte(text))); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Message) Serialize() ([]byte, error) {
+	return m.raw, nil
+}
+
+func (m *Message) Deserialize(b []byte) error {
+	text, err := unescapeString(b)
+	if err != nil {
+		return err
+	}
+	parts, err := parseMessage(string(text))
+	if err != nil {
+		return err
+	}
+	m.raw, m.parts = b, parts
+	return nil
+}
+
+// Format formats the message in the language of locale, a BCP 47 tag like
+// "en-US". Plural, selectordinal and number arguments must be numbers of a
+// Go numeric type, date and time arguments time.Time values. Other arguments
+// are formatted with fmt. Numbers are formatted without grouping separators
+// and dates and times in English.
+func (m *Message) Format(locale string, args map[string]interface{}) (string, error) {
+	var b strings.Builder
+	if err := formatMessageParts(&b, m.parts, locale, args, 0); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// ValidateMessages checks that the value of every leaf of node is a valid
+// ICU message. The errors found are returned in a MultiError, with the paths
+// of their leaves and their positions in the messages.
+func ValidateMessages(node Node) error {
+	if node == nil {
+		return fmt.Errorf("node is nil")
+	}
+	var errs MultiError
+	var walk func(node Node, path [][]byte) error
+	walk = func(node Node, path [][]byte) error {
+		if nodes := node.Nodes(); len(nodes) > 0 {
+			for _, child := range nodes {
+				if child == nil {
+					return fmt.Errorf("invalid node: node.Nodes() contained nil")
+				}
+				if err := walk(child, append(path[:len(path):len(path)], child.Key())); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		value := node.Value()
+		if value == nil {
+			return fmt.Errorf("invalid node: len(node.Nodes()) == 0 and node.Value() == nil")
+		}
+		raw, err := value.Serialize()
+		if err != nil {
+			return err
+		}
+		text, err := unescapeString(raw)
+		if err != nil {
+			errs = append(errs, &SyntaxError{Path: path, Err: err})
+			return nil
+		}
+		if _, err := parseMessage(string(text)); err != nil {
+			serr := err.(*SyntaxError)
+			serr.Path = path
+			errs = append(errs, serr)
+		}
+		return nil
+	}
+	var path [][]byte
+	if node.Key() != nil {
+		path = [][]byte{node.Key()}
+	}
+	if err := walk(node, path); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+type messageParser struct {
+	text string
+	i    int
+}
+
+func parseMessage(text string) ([]messagePart, error) {
+	p := &messageParser{text: text}
+	return p.parseParts(0, false)
+}
+
+func (p *messageParser) errorf(format string, args ...interface{}) error {
+	pos := Position{Line: 1, Column: 1}
+	for i := 0; i < p.i; i++ {
+		pos.advance(p.text[i])
+	}
+	return &SyntaxError{Pos: pos, Err: fmt.Errorf(format, args...)}
+}
+
+// parseParts parses a message, up to the '}' ending it if it is nested.
+// '#' is the number being formatted in the cases of plural arguments.
+func (p *messageParser) parseParts(depth int, plural bool) ([]messagePart, error) {
+	var parts []messagePart
+	var text []byte
+	flush := func() {
+		if len(text) > 0 {
+			parts = append(parts, messagePart{text: string(text)})
+			text = nil
+		}
+	}
+	for p.i < len(p.text) {
+		switch c := p.text[p.i]; {
+		case c == '\'':
+			p.i++
+			text = p.readQuoted(text, plural)
+		case c == '{':
+			flush()
+			arg, err := p.parseArg(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, messagePart{arg: arg})
+		case c == '}':
+			if depth == 0 {
+				return nil, p.errorf("unexpected '}'")
+			}
+			flush()
+			return parts, nil
+		case c == '#' && plural:
+			flush()
+			parts = append(parts, messagePart{hash: true})
+			p.i++
+		default:
+			text = append(text, c)
+			p.i++
+		}
+	}
+	if depth > 0 {
+		return nil, p.errorf("expected '}'")
+	}
+	flush()
+	return parts, nil
+}
+
+// readQuoted reads the text following an apostrophe. Two apostrophes are
+// one, and an apostrophe before a syntax character starts quoted text, up to
+// the next apostrophe. Other apostrophes are literal.
+func (p *messageParser) readQuoted(text []byte, plural bool) []byte {
+	if p.i < len(p.text) && p.text[p.i] == '\'' {
+		p.i++
+		return append(text, '\'')
+	}
+	if p.i == len(p.text) || !(strings.IndexByte("{}|", p.text[p.i]) >= 0 || plural && p.text[p.i] == '#') {
+		return append(text, '\'')
+	}
+	for p.i < len(p.text) {
+		c := p.text[p.i]
+		p.i++
+		if c == '\'' {
+			if p.i == len(p.text) || p.text[p.i] != '\'' {
+				break
+			}
+			p.i++
+		}
+		text = append(text, c)
+	}
+	return text
+}
+
+// parseArg parses an argument, from its '{' to its '}'
+func (p *messageParser) parseArg(depth int) (*messageArg, error) {
+	p.i++
+	p.skipSpace()
+	arg := &messageArg{name: p.readWord()}
+	if arg.name == "" {
+		return nil, p.errorf("expected argument name")
+	}
+	p.skipSpace()
+	if p.consume('}') {
+		return arg, nil
+	}
+	if !p.consume(',') {
+		return nil, p.errorf("expected ',' or '}'")
+	}
+	p.skipSpace()
+	start := p.i
+	arg.typ = p.readWord()
+	switch arg.typ {
+	case "":
+		return nil, p.errorf("expected argument type")
+	case "number", "date", "time":
+		p.skipSpace()
+		if p.consume('}') {
+			return arg, nil
+		}
+		if !p.consume(',') {
+			return nil, p.errorf("expected ',' or '}'")
+		}
+		p.skipSpace()
+		start := p.i
+		end := strings.IndexByte(p.text[p.i:], '}')
+		if end < 0 {
+			p.i = len(p.text)
+			return nil, p.errorf("expected '}'")
+		}
+		arg.style = strings.TrimSpace(p.text[p.i : p.i+end])
+		if !isMessageStyle(arg.typ, arg.style) {
+			p.i = start
+			return nil, p.errorf("unsupported %s style \"%s\"", arg.typ, arg.style)
+		}
+		p.i += end + 1
+		return arg, nil
+	case "plural", "selectordinal", "select":
+		p.skipSpace()
+		if !p.consume(',') {
+			return nil, p.errorf("expected ','")
+		}
+		return arg, p.parseCases(arg, depth)
+	}
+	p.i = start
+	return nil, p.errorf("unsupported argument type \"%s\"", arg.typ)
+}
+
+// parseCases parses the cases of a plural, selectordinal or select argument,
+// and the '}' ending it
+func (p *messageParser) parseCases(arg *messageArg, depth int) error {
+	plural := arg.typ != "select"
+	p.skipSpace()
+	if plural && strings.HasPrefix(p.text[p.i:], "offset:") {
+		p.i += len("offset:")
+		p.skipSpace()
+		n, ok := p.readNumber()
+		if !ok {
+			return p.errorf("expected offset")
+		}
+		arg.offset = n
+	}
+	seen := make(map[string]bool)
+	for {
+		p.skipSpace()
+		if p.i == len(p.text) {
+			return p.errorf("expected '}'")
+		}
+		if p.consume('}') {
+			break
+		}
+		start := p.i
+		var selector string
+		if plural && p.consume('=') {
+			n, ok := p.readNumber()
+			if !ok {
+				return p.errorf("expected number")
+			}
+			selector = "=" + strconv.FormatFloat(n, 'f', -1, 64)
+		} else {
+			selector = p.readWord()
+			if selector == "" {
+				return p.errorf("expected selector")
+			}
+			if plural && !isPluralCategory(selector) {
+				p.i = start
+				return p.errorf("invalid plural category \"%s\"", selector)
+			}
+		}
+		if seen[selector] {
+			p.i = start
+			return p.errorf("duplicate selector \"%s\"", selector)
+		}
+		seen[selector] = true
+		p.skipSpace()
+		if !p.consume('{') {
+			return p.errorf("expected '{'")
+		}
+		parts, err := p.parseParts(depth, plural)
+		if err != nil {
+			return err
+		}
+		p.i++
+		arg.cases = append(arg.cases, messageCase{selector: selector, parts: parts})
+	}
+	if !seen[PluralOther] {
+		p.i--
+		return p.errorf("%s argument \"%s\" has no \"other\" case", arg.typ, arg.name)
+	}
+	return nil
+}
+
+func (p *messageParser) skipSpace() {
+	for p.i < len(p.text) {
+		r, n := utf8.DecodeRuneInString(p.text[p.i:])
+		if !unicode.IsSpace(r) {
+			return
+		}
+		p.i += n
+	}
+}
+
+func (p *messageParser) consume(c byte) bool {
+	if p.i < len(p.text) && p.text[p.i] == c {
+		p.i++
+		return true
+	}
+	return false
+}
+
+// readWord reads an argument name, a type or a selector
+func (p *messageParser) readWord() string {
+	start := p.i
+	for p.i < len(p.text) {
+		r, n := utf8.DecodeRuneInString(p.text[p.i:])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			break
+		}
+		p.i += n
+	}
+	return p.text[start:p.i]
+}
+
+func (p *messageParser) readNumber() (float64, bool) {
+	start := p.i
+	p.consume('-')
+	for p.i < len(p.text) && (p.text[p.i] >= '0' && p.text[p.i] <= '9' || p.text[p.i] == '.') {
+		p.i++
+	}
+	n, err := strconv.ParseFloat(p.text[start:p.i], 64)
+	if err != nil {
+		p.i = start
+		return 0, false
+	}
+	return n, true
+}
+
+func isMessageStyle(typ, style string) bool {
+	switch typ {
+	case "number":
+		return style == "" || style == "integer" || style == "percent"
+	default:
+		return style == "" || style == "short" || style == "medium" || style == "long" || style == "full"
+	}
+}
+
+func formatMessageParts(b *strings.Builder, parts []messagePart, locale string, args map[string]interface{}, n float64) error {
+	for _, part := range parts {
+		switch {
+		case part.hash:
+			b.WriteString(formatMessageNumber(n, ""))
+		case part.arg != nil:
+			if err := part.arg.format(b, locale, args); err != nil {
+				return err
+			}
+		default:
+			b.WriteString(part.text)
+		}
+	}
+	return nil
+}
+
+func (arg *messageArg) format(b *strings.Builder, locale string, args map[string]interface{}) error {
+	v, ok := args[arg.name]
+	if !ok {
+		return fmt.Errorf("missing argument \"%s\"", arg.name)
+	}
+	switch arg.typ {
+	case "":
+		fmt.Fprint(b, v)
+		return nil
+	case "date", "time":
+		t, ok := v.(time.Time)
+		if !ok {
+			return fmt.Errorf("argument \"%s\" is not a time.Time", arg.name)
+		}
+		b.WriteString(t.Format(messageTimeLayouts[arg.typ+" "+arg.style]))
+		return nil
+	case "select":
+		return formatMessageParts(b, arg.choose(fmt.Sprint(v)), locale, args, 0)
+	}
+	n, ok := toFloat(v)
+	if !ok {
+		return fmt.Errorf("argument \"%s\" is not a number", arg.name)
+	}
+	switch arg.typ {
+	case "number":
+		b.WriteString(formatMessageNumber(n, arg.style))
+		return nil
+	case "selectordinal":
+		return formatMessageParts(b, arg.choose("="+strconv.FormatFloat(n, 'f', -1, 64), ordinalCategory(locale, n-arg.offset)), locale, args, n-arg.offset)
+	}
+	return formatMessageParts(b, arg.choose("="+strconv.FormatFloat(n, 'f', -1, 64), PluralCategory(locale, n-arg.offset)), locale, args, n-arg.offset)
+}
+
+// choose returns the parts of the first of the selectors the argument has a
+// case for, or of its "other" case
+func (arg *messageArg) choose(selectors ...string) []messagePart {
+	for _, selector := range append(selectors, PluralOther) {
+		for _, c := range arg.cases {
+			if c.selector == selector {
+				return c.parts
+			}
+		}
+	}
+	return nil
+}
+
+var messageTimeLayouts = map[string]string{
+	"date ":       "Jan 2, 2006",
+	"date short":  "1/2/06",
+	"date medium": "Jan 2, 2006",
+	"date long":   "January 2, 2006",
+	"date full":   "Monday, January 2, 2006",
+	"time ":       "3:04:05 PM",
+	"time short":  "3:04 PM",
+	"time medium": "3:04:05 PM",
+	"time long":   "3:04:05 PM MST",
+	"time full":   "3:04:05 PM MST",
+}
+
+func formatMessageNumber(n float64, style string) string {
+	switch style {
+	case "integer":
+		return strconv.FormatFloat(math.Round(n), 'f', 0, 64)
+	case "percent":
+		return strconv.FormatFloat(math.Round(n*100), 'f', 0, 64) + "%"
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
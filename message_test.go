@@ -0,0 +1,188 @@
+package jsontree
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageFormat(t *testing.T) {
+	date := time.Date(2024, 3, 5, 14, 7, 9, 0, time.UTC)
+	tests := []struct {
+		msg    string
+		locale string
+		args   map[string]interface{}
+		want   string
+	}{
+		{
+			msg:  "Hello, {name}!",
+			args: map[string]interface{}{"name": "World"},
+			want: "Hello, World!",
+		},
+		{
+			msg:  "{count, plural, one {# item} other {# items}}",
+			args: map[string]interface{}{"count": 1},
+			want: "1 item",
+		},
+		{
+			msg:  "{count, plural, one {# item} other {# items}}",
+			args: map[string]interface{}{"count": 2.5},
+			want: "2.5 items",
+		},
+		{
+			msg:  "{count, plural, =0 {no items} one {# item} other {# items}}",
+			args: map[string]interface{}{"count": uint8(0)},
+			want: "no items",
+		},
+		{
+			msg:  "{n, plural, offset:1 =0 {nobody} =1 {{name}} one {{name} and # other} other {{name} and # others}}",
+			args: map[string]interface{}{"n": 2, "name": "Ann"},
+			want: "Ann and 1 other",
+		},
+		{
+			msg:    "{n, plural, one {# файл} few {# файла} many {# файлов} other {# файла}}",
+			locale: "ru-RU",
+			args:   map[string]interface{}{"n": 22},
+			want:   "22 файла",
+		},
+		{
+			msg:    "{n, plural, one {# plik} few {# pliki} many {# plików} other {# pliku}}",
+			locale: "pl",
+			args:   map[string]interface{}{"n": 12},
+			want:   "12 plików",
+		},
+		{
+			msg:    "{n, plural, one {# fichier} other {# fichiers}}",
+			locale: "fr",
+			args:   map[string]interface{}{"n": 0},
+			want:   "0 fichier",
+		},
+		{
+			msg:    "{n, plural, one {# item} other {# items}}",
+			locale: "ja",
+			args:   map[string]interface{}{"n": 1},
+			want:   "1 items",
+		},
+		{
+			msg:  "{n, selectordinal, one {#st} two {#nd} few {#rd} other {#th}}",
+			args: map[string]interface{}{"n": 23},
+			want: "23rd",
+		},
+		{
+			msg:  "{gender, select, female {She} male {He} other {They}} {gender, select, female {is} other {are}} here",
+			args: map[string]interface{}{"gender": "male"},
+			want: "He are here",
+		},
+		{
+			msg:  "{p, number, percent} of {n, number, integer} on {d, date, long} at {d, time, short}",
+			args: map[string]interface{}{"p": 0.25, "n": 2.6, "d": date},
+			want: "25% of 3 on March 5, 2024 at 2:07 PM",
+		},
+		{
+			msg:  "It''s '{quoted}' and '#' {n, plural, other {'#' is #}}",
+			args: map[string]interface{}{"n": 5},
+			want: "It's {quoted} and '#' # is 5",
+		},
+	}
+	for _, test := range tests {
+		m, err := ParseMessage(test.msg)
+		if err != nil {
+			t.Errorf("%s: ParseMessage() error: %v", test.msg, err)
+			continue
+		}
+		locale := test.locale
+		if locale == "" {
+			locale = "en"
+		}
+		got, err := m.Format(locale, test.args)
+		if err != nil {
+			t.Errorf("%s: Format() error: %v", test.msg, err)
+		} else if got != test.want {
+			t.Errorf("%s: Wrong message\nWant %s\nGot  %s", test.msg, test.want, got)
+		}
+	}
+}
+
+func TestMessageFormatErrors(t *testing.T) {
+	tests := []struct {
+		msg  string
+		args map[string]interface{}
+		err  string
+	}{
+		{
+			msg: "{name}",
+			err: `missing argument "name"`,
+		},
+		{
+			msg:  "{n, plural, other {#}}",
+			args: map[string]interface{}{"n": "1"},
+			err:  `argument "n" is not a number`,
+		},
+		{
+			msg:  "{d, date}",
+			args: map[string]interface{}{"d": 1},
+			err:  `argument "d" is not a time.Time`,
+		},
+	}
+	for _, test := range tests {
+		m, err := ParseMessage(test.msg)
+		if err != nil {
+			t.Fatalf("%s: ParseMessage() error: %v", test.msg, err)
+		}
+		if _, err := m.Format("en", test.args); err == nil || err.Error() != test.err {
+			t.Errorf("%s: Wrong error\nWant %s\nGot  %v", test.msg, test.err, err)
+		}
+	}
+}
+
+func TestMessageDeserialize(t *testing.T) {
+	tests := []struct {
+		raw string
+		err string
+	}{
+		{raw: `Hi {name}\n{n, plural, one {#} other {#}}`},
+		{raw: `{n, plural, one {#}}`, err: `1:20: plural argument "n" has no "other" case (at "")`},
+		{raw: `{n, plural, few {a} few {b} other {c}}`, err: `1:21: duplicate selector "few" (at "")`},
+		{raw: `{n, plural, lots {#} other {#}}`, err: `1:13: invalid plural category "lots" (at "")`},
+		{raw: `{n, select, a {x}`, err: `1:18: expected '}' (at "")`},
+		{raw: `\n{n, choice, 0#a}`, err: `2:5: unsupported argument type "choice" (at "")`},
+		{raw: `{n, number, currency}`, err: `1:13: unsupported number style "currency" (at "")`},
+		{raw: `{n, plural, =x {a} other {b}}`, err: `1:14: expected number (at "")`},
+		{raw: `a } b`, err: `1:3: unexpected '}' (at "")`},
+		{raw: `{}`, err: `1:2: expected argument name (at "")`},
+		{raw: `{n;}`, err: `1:3: expected ',' or '}' (at "")`},
+		{raw: `\u`, err: "invalid unicode escape"},
+	}
+	for _, test := range tests {
+		m := new(Message)
+		err := m.Deserialize([]byte(test.raw))
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("%s: Deserialize() error: %v", test.raw, err)
+			} else if got, _ := m.Serialize(); string(got) != test.raw {
+				t.Errorf("%s: Wrong serialized value %s", test.raw, got)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: Wrong error\nWant %s\nGot  %v", test.raw, test.err, err)
+		}
+	}
+}
+
+func TestValidateMessages(t *testing.T) {
+	node := &testNode{}
+	if err := DeserializeNode(node, strings.NewReader(`{"en":{"ok":"{n, plural, one {#} other {#}}","menu":{"bad":"{n, plural, one {#}}","worse":"{x"}}}`)); err != nil {
+		t.Fatalf("DeserializeNode() error: %v", err)
+	}
+	want := "1:20: plural argument \"n\" has no \"other\" case (at \"en.menu.bad\")\n1:3: expected ',' or '}' (at \"en.menu.worse\")"
+	if err := ValidateMessages(node); err == nil || err.Error() != want {
+		t.Errorf("Wrong error\nWant %s\nGot  %v", want, err)
+	}
+	if err := ValidateMessages(node.nodes[0]); err != nil {
+		t.Errorf("ValidateMessages() error: %v", err)
+	}
+	if err := ValidateMessages(nil); err == nil {
+		t.Errorf("ValidateMessages(nil) returned no error")
+	}
+}
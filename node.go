@@ -2,6 +2,7 @@ package jsontree
 
 import (
 	"bytes"
+	"fmt"
 )
 
 type Value interface {
@@ -17,6 +18,15 @@ type Node interface {
 	AddNode(key []byte) Node
 }
 
+// CommentNode is a Node that keeps the comments of the formats that have
+// them, like .properties and INI files. The comment is the text of the
+// comment lines before the key, without their comment markers.
+type CommentNode interface {
+	Node
+	Comment() []byte
+	SetComment(comment []byte)
+}
+
 func getNode(node Node, path ...[]byte) Node {
 	// no need to check len(path). get is only called by getOrAdd, which does that already
 	key := path[0]
@@ -48,6 +58,107 @@ func getOrAddNode(node Node, path ...[]byte) Node {
 	}
 }
 
+// pathCache holds the nodes of the last path passed to getOrAdd, so that the
+// leaves of an object are added without searching the tree from its root
+type pathCache struct {
+	nodes []Node // nodes[i] is the node of path[:i+1]
+}
+
+// getOrAdd is like getOrAddNode, but starts from the nodes it shares with the
+// last path
+func (c *pathCache) getOrAdd(node Node, path [][]byte) Node {
+	i := 0
+	for i < len(c.nodes) && i < len(path) && keyEqual(c.nodes[i].Key(), path[i]) {
+		i++
+	}
+	c.nodes = c.nodes[:i]
+	if i > 0 {
+		node = c.nodes[i-1]
+	}
+	for ; i < len(path); i++ {
+		n := getNode(node, path[i])
+		if n == nil {
+			n = node.AddNode(path[i])
+		}
+		c.nodes = append(c.nodes, n)
+		node = n
+	}
+	return node
+}
+
 func keyEqual(key1, key2 []byte) bool {
 	return bytes.Equal(key1, key2)
 }
+
+// treeBuilder adds the keys read from other formats to root. Like the JSON
+// parser, it expects a single top-level key unless multi is set.
+type treeBuilder struct {
+	root    Node
+	multi   bool
+	hasRoot bool
+	leaves  map[string]bool // the paths of leaves, as joined by pathString
+	// positions are those of the leaves, for formats reporting duplicates
+	positions map[string]Position
+}
+
+// node returns the node at path, adding it if needed
+func (b *treeBuilder) node(path [][]byte) (Node, error) {
+	if b.leaves != nil {
+		for i := 1; i < len(path); i++ {
+			if b.leaves[pathString(path[:i])] {
+				return nil, fmt.Errorf("key \"%s\" is both a value and a parent", bytes.Join(path[:i], []byte{'.'}))
+			}
+		}
+	}
+	if b.multi {
+		return getOrAddNode(b.root, path...), nil
+	}
+	if !b.hasRoot {
+		b.root.SetKey(path[0])
+		b.hasRoot = true
+	} else if !keyEqual(b.root.Key(), path[0]) {
+		return nil, fmt.Errorf("expected 1 root node")
+	}
+	if len(path) == 1 {
+		return b.root, nil
+	}
+	return getOrAddNode(b.root, path[1:]...), nil
+}
+
+// leaf returns the node at path, like node, for formats where a key can be
+// both a value and a parent of other keys. That is reported as an error, as
+// a Node can not be both.
+func (b *treeBuilder) leaf(path [][]byte) (Node, error) {
+	if b.leaves == nil {
+		b.leaves = make(map[string]bool)
+	}
+	node, err := b.node(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(node.Nodes()) > 0 {
+		return nil, fmt.Errorf("key \"%s\" is both a value and a parent", bytes.Join(path, []byte{'.'}))
+	}
+	b.leaves[pathString(path)] = true
+	return node, nil
+}
+
+// duplicate records the leaf at path, found at pos, and applies the
+// duplicate policy of opts if it was found before. It reports whether the
+// leaf is to be ignored, as the first one wins.
+func (b *treeBuilder) duplicate(path [][]byte, pos Position, opts *DecodeOptions) (bool, error) {
+	if b.positions == nil {
+		b.positions = make(map[string]Position)
+	}
+	first, ok := b.positions[pathString(path)]
+	if !ok {
+		b.positions[pathString(path)] = pos
+		return false, nil
+	}
+	policy, err := opts.duplicatePolicy(&DuplicateKeyError{Path: path, First: first, Second: pos})
+	return policy == DuplicateFirstWins, err
+}
+
+func pathString(path [][]byte) string {
+	return string(bytes.Join(path, []byte{0}))
+}
@@ -1,6 +1,9 @@
 package jsontree
 
-import "testing"
+import (
+	"bytes"
+	"testing"
+)
 
 func TestGetNode(t *testing.T) {
 	node := &testNode{
@@ -103,6 +106,22 @@ func TestNodeGetOrAdd(t *testing.T) {
 	}
 }
 
+func TestPathCache(t *testing.T) {
+	// The nodes returned are those getOrAddNode returns, whatever the last path
+	node := &testNode{key: key("root")}
+	var cache pathCache
+	for _, path := range []string{"a.x", "a.y", "b", "a.z.1", "a.z.2", "a.x", "c.x"} {
+		keys := bytes.Split(key(path), []byte{'.'})
+		got := cache.getOrAdd(node, keys)
+		if want := getOrAddNode(node, keys...); got != want {
+			t.Errorf("getOrAdd(%s) = %s, want %s", path, nodeString(got), nodeString(want))
+		}
+	}
+	if len(node.nodes) != 3 || len(node.nodes[0].nodes) != 3 || len(node.nodes[0].nodes[2].nodes) != 2 {
+		t.Errorf("Wrong tree %s", nodeString(node))
+	}
+}
+
 // ========== Utility ==========
 
 type testNode struct {
@@ -0,0 +1,128 @@
+package jsontree
+
+import (
+	"fmt"
+	"io"
+)
+
+// DecodeOptions configures how documents are deserialized. The zero value
+// imposes no limits and is what DeserializeNode and DeserializeBytes use.
+//
+// The limits guard against hostile input. A limit of 0 means no limit.
+type DecodeOptions struct {
+	MaxDepth    int // maximum nesting depth of keys, the root key being at depth 1
+	MaxKeyLen   int // maximum length of a key, in bytes
+	MaxValueLen int // maximum length of a value, in bytes
+	MaxNodes    int // maximum number of keys in the document
+	MaxBytes    int // maximum size of the document, in bytes
+
+	// Duplicates is the policy for keys occurring more than once in the same
+	// object. OnDuplicate is called for each duplicate key if Duplicates is
+	// DuplicateCallback, and returns the policy to apply to that key.
+	Duplicates  DuplicatePolicy
+	OnDuplicate func(err *DuplicateKeyError) DuplicatePolicy
+
+	// MultipleRoots maps the keys of the top level object onto the children
+	// of the node, rather than requiring the object to have exactly one key
+	// that becomes the node itself. The key of the node is left untouched.
+	MultipleRoots bool
+
+	// RecoverErrors makes the parser carry on after a syntax error, from the
+	// next ',' or '}' of the object the error was found in. Every error is
+	// collected in a MultiError, which is returned along with what could be
+	// read of the document.
+	RecoverErrors bool
+
+	// Dialect is the syntax of the document. Comments of the JSONC and JSON5
+	// dialects are trivia, kept by TriviaNodes.
+	Dialect Dialect
+
+	// Schema, if set, validates the node once the document has been read,
+	// as Schema.Validate does: it describes the value of the root, or the top
+	// level object with MultipleRoots. The violations are returned in a
+	// ValidationErrors, the tree being left as read.
+	Schema *Schema
+}
+
+// EncodeOptions configures how nodes are serialized. The zero value is what
+// SerializeNode and NewWriter use.
+type EncodeOptions struct {
+	// MultipleRoots writes the children of the node as the keys of the top
+	// level object, leaving out the key of the node itself. It is the
+	// counterpart of DecodeOptions.MultipleRoots.
+	MultipleRoots bool
+
+	// Canonical writes the canonical form of RFC 8785 (JCS): keys are sorted
+	// by their UTF-16 code units and strings use minimal escaping, so the
+	// same tree always serializes to the same bytes, whatever the order of
+	// Nodes() and however its keys and values are escaped. Values are always
+	// strings, so the canonical form of numbers never applies.
+	Canonical bool
+
+	// Less, if set, orders the children of every node, without modifying the
+	// tree. See ByKey and NaturalOrder. It is ignored if Canonical is set.
+	Less KeyLess
+
+	// OmitComments leaves the comments out of the trivia of TriviaNodes,
+	// so that a document read as JSONC or JSON5 is written as plain JSON.
+	OmitComments bool
+
+	// Schema, if set, validates the node before it is written, as
+	// Schema.Validate does. Nothing is written if it is not valid. A Writer
+	// validates every node passed to WriteNode.
+	Schema *Schema
+}
+
+// LimitError is returned when a document exceeds one of the limits in
+// DecodeOptions. Limit is the name of the field of the exceeded limit.
+type LimitError struct {
+	Limit string
+	Max   int
+}
+
+func (err *LimitError) Error() string {
+	return fmt.Sprintf("document exceeds %s (%d)", err.Limit, err.Max)
+}
+
+// readAll reads all of r, failing if it is longer than max bytes, if max > 0
+func readAll(r io.Reader, max int) ([]byte, error) {
+	if max <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, int64(max)+1))
+	if err == nil && len(data) > max {
+		return nil, &LimitError{Limit: "MaxBytes", Max: max}
+	}
+	return data, err
+}
+
+func (opts DecodeOptions) DeserializeNode(node Node, r io.Reader) error {
+	src := newReaderSource(r)
+	src.maxBytes = opts.MaxBytes
+	return deserializeNode(node, newParser(src, opts))
+}
+
+func (opts DecodeOptions) DeserializeBytes(node Node, data []byte) error {
+	if max := opts.MaxBytes; max > 0 && len(data) > max {
+		return &LimitError{Limit: "MaxBytes", Max: max}
+	}
+	return deserializeNode(node, newParser(newSliceSource(data), opts))
+}
+
+func (opts DecodeOptions) NewScanner(data []byte) *Scanner {
+	s := &Scanner{p: newParser(newSliceSource(data), opts)}
+	if max := opts.MaxBytes; max > 0 && len(data) > max {
+		s.p.err = &LimitError{Limit: "MaxBytes", Max: max}
+	}
+	return s
+}
+
+func (opts EncodeOptions) SerializeNode(node Node, w io.Writer) error {
+	return serializeDocument(node, w, &opts)
+}
+
+func (opts EncodeOptions) NewWriter(w io.Writer) *Writer {
+	writer := NewWriter(w)
+	writer.opts = opts
+	return writer
+}
@@ -0,0 +1,141 @@
+package jsontree
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecodeOptionsLimits(t *testing.T) {
+	in := `{"root":{"a":"v1","bb":{"c":"value"}}}`
+	tests := []struct {
+		name string
+		opts DecodeOptions
+		err  error
+	}{
+		{"no limits", DecodeOptions{}, nil},
+		{"limits not exceeded", DecodeOptions{MaxDepth: 3, MaxKeyLen: 4, MaxValueLen: 5, MaxNodes: 4, MaxBytes: len(in)}, nil},
+		{"MaxDepth", DecodeOptions{MaxDepth: 2}, &LimitError{Limit: "MaxDepth", Max: 2}},
+		{"MaxKeyLen", DecodeOptions{MaxKeyLen: 3}, &LimitError{Limit: "MaxKeyLen", Max: 3}},
+		{"MaxValueLen", DecodeOptions{MaxValueLen: 4}, &LimitError{Limit: "MaxValueLen", Max: 4}},
+		{"MaxNodes", DecodeOptions{MaxNodes: 3}, &LimitError{Limit: "MaxNodes", Max: 3}},
+		{"MaxBytes", DecodeOptions{MaxBytes: len(in) - 1}, &LimitError{Limit: "MaxBytes", Max: len(in) - 1}},
+	}
+	for _, test := range tests {
+		// Reader
+		{
+			err := test.opts.DeserializeNode(new(testNode), strings.NewReader(in))
+			if !errEqual(test.err, err) {
+				t.Errorf("%s: DeserializeNode() returned wrong error\nWant %v\nGot  %v", test.name, test.err, err)
+			}
+			if test.err != nil {
+				if _, ok := err.(*LimitError); !ok {
+					t.Errorf("%s: DeserializeNode() returned %T, want *LimitError", test.name, err)
+				}
+			}
+		}
+		// Bytes
+		{
+			err := test.opts.DeserializeBytes(new(testNode), []byte(in))
+			if !errEqual(test.err, err) {
+				t.Errorf("%s: DeserializeBytes() returned wrong error\nWant %v\nGot  %v", test.name, test.err, err)
+			}
+		}
+	}
+}
+
+func TestDecodeOptionsMaxBytesStream(t *testing.T) {
+	// A document larger than MaxBytes must not be read into memory in full
+	r := &countingReader{r: io.MultiReader(strings.NewReader(`{"a":"`), infiniteReader('x'))}
+	opts := DecodeOptions{MaxBytes: 1 << 16}
+	want := &LimitError{Limit: "MaxBytes", Max: 1 << 16}
+	if err := opts.DeserializeNode(new(testNode), r); !errEqual(want, err) {
+		t.Fatalf("DeserializeNode() returned wrong error\nWant %v\nGot  %v", want, err)
+	}
+	if r.n > 2<<16 {
+		t.Errorf("DeserializeNode() read %d bytes, limit was %d", r.n, 1<<16)
+	}
+}
+
+type infiniteReader byte
+
+func (r infiniteReader) Read(p []byte) (int, error) {
+	copy(p, bytes.Repeat([]byte{byte(r)}, len(p)))
+	return len(p), nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += n
+	return n, err
+}
+
+func TestMultipleRoots(t *testing.T) {
+	tests := []struct {
+		in   string
+		want *testNode
+	}{
+		{
+			in: `{"a":"v1","b":{"c":"v2"},"d":"v3"}`,
+			want: &testNode{key: key("doc"), nodes: []*testNode{
+				{key: key("a"), value: val("v1")},
+				{key: key("b"), nodes: []*testNode{{key: key("c"), value: val("v2")}}},
+				{key: key("d"), value: val("v3")},
+			}},
+		},
+		{
+			in:   `{"a":"v1"}`,
+			want: &testNode{key: key("doc"), nodes: []*testNode{{key: key("a"), value: val("v1")}}},
+		},
+		{
+			in:   `{}`,
+			want: &testNode{key: key("doc")},
+		},
+	}
+	decode := DecodeOptions{MultipleRoots: true}
+	encode := EncodeOptions{MultipleRoots: true}
+	for _, test := range tests {
+		node := &testNode{key: key("doc")}
+		if err := decode.DeserializeNode(node, strings.NewReader(test.in)); err != nil {
+			t.Errorf("%s: DeserializeNode() error: %v", test.in, err)
+			continue
+		}
+		if !nodeEqual(node, test.want) {
+			t.Errorf("%s: Node was not as expected\nWant %v\nGot  %v", test.in, nodeString(test.want), nodeString(node))
+		}
+		node = &testNode{key: key("doc")}
+		if err := decode.DeserializeBytes(node, []byte(test.in)); err != nil {
+			t.Errorf("%s: DeserializeBytes() error: %v", test.in, err)
+		} else if !nodeEqual(node, test.want) {
+			t.Errorf("%s: DeserializeBytes() node was not as expected\nWant %v\nGot  %v", test.in, nodeString(test.want), nodeString(node))
+		}
+
+		// Round trip through SerializeNode and Writer
+		var buf bytes.Buffer
+		if err := encode.SerializeNode(node, &buf); err != nil {
+			t.Errorf("%s: SerializeNode() error: %v", test.in, err)
+		} else if got := buf.String(); got != test.in {
+			t.Errorf("SerializeNode() = %s, want %s", got, test.in)
+		}
+		buf.Reset()
+		w := encode.NewWriter(&buf)
+		if err := w.WriteNode(node); err != nil {
+			t.Errorf("%s: WriteNode() error: %v", test.in, err)
+		} else if err := w.Close(); err != nil {
+			t.Errorf("%s: Close() error: %v", test.in, err)
+		} else if got := buf.String(); got != test.in {
+			t.Errorf("Writer wrote %s, want %s", got, test.in)
+		}
+	}
+	// Empty objects are only allowed at the top level
+	want := &DeserializeError{Got: '}', Want: []byte{'"'}}
+	if err := decode.DeserializeNode(new(testNode), strings.NewReader(`{"a":{}}`)); !errEqual(want, err) {
+		t.Errorf("Wrong error\nWant %v\nGot  %v", want, err)
+	}
+}
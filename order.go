@@ -0,0 +1,85 @@
+package jsontree
+
+import (
+	"bytes"
+	"sort"
+)
+
+// KeyLess reports whether the node with key a should be written before the
+// node with key b. Keys are passed as they are stored, escapes included.
+type KeyLess func(a, b []byte) bool
+
+// ByKey orders keys by their bytes
+func ByKey(a, b []byte) bool {
+	return bytes.Compare(a, b) < 0
+}
+
+// NaturalOrder orders keys by their bytes, except that runs of digits are
+// compared by their numeric value, so that "item2" comes before "item10".
+// Numbers that are equal in value are ordered by their number of leading
+// zeros.
+func NaturalOrder(a, b []byte) bool {
+	for len(a) > 0 && len(b) > 0 {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			na, nb := digitRun(a), digitRun(b)
+			if c := compareNumbers(a[:na], b[:nb]); c != 0 {
+				return c < 0
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[na:], b[nb:]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func digitRun(bs []byte) int {
+	n := 0
+	for n < len(bs) && isDigit(bs[n]) {
+		n++
+	}
+	return n
+}
+
+// compareNumbers compares two runs of digits by their value
+func compareNumbers(a, b []byte) int {
+	a, b = bytes.TrimLeft(a, "0"), bytes.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return bytes.Compare(a, b)
+}
+
+// sortNodes returns a copy of nodes sorted by less. The order of nodes with
+// equal keys is kept.
+func sortNodes(nodes []Node, less KeyLess) []Node {
+	sorted := make([]Node, len(nodes))
+	copy(sorted, nodes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i] == nil || sorted[j] == nil {
+			return false // reported by serializeNodes
+		}
+		return less(sorted[i].Key(), sorted[j].Key())
+	})
+	return sorted
+}
+
+// order returns nodes in the order they are serialized with opts
+func (opts *EncodeOptions) order(nodes []Node) ([]Node, error) {
+	if opts.Canonical {
+		return sortCanonical(nodes)
+	} else if opts.Less != nil {
+		return sortNodes(nodes, opts.Less), nil
+	}
+	return nodes, nil
+}
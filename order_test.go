@@ -0,0 +1,82 @@
+package jsontree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNaturalOrder(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"a", "b", true},
+		{"b", "a", false},
+		{"a", "a", false},
+		{"a", "ab", true},
+		{"item2", "item10", true},
+		{"item10", "item2", false},
+		{"item10", "item10a", true},
+		{"2", "02", true},
+		{"02", "2", false},
+		{"a1b2", "a1b10", true},
+		{"1", "a", true},
+		{"99999999999999999999999", "100000000000000000000000", true},
+	}
+	for _, test := range tests {
+		if got := NaturalOrder(key(test.a), key(test.b)); got != test.want {
+			t.Errorf("NaturalOrder(%s, %s) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestEncodeOptionsLess(t *testing.T) {
+	node := &testNode{key: key("root"), nodes: []*testNode{
+		{key: key("item10"), value: val("1")},
+		{key: key("b"), nodes: []*testNode{
+			{key: key("z"), value: val("2")},
+			{key: key("y"), value: val("3")},
+		}},
+		{key: key("item2"), value: val("4")},
+		{key: key("B"), value: val("5")},
+	}}
+	tests := []struct {
+		name string
+		less KeyLess
+		want string
+	}{
+		{"unsorted", nil, `{"root":{"item10":"1","b":{"z":"2","y":"3"},"item2":"4","B":"5"}}`},
+		{"by key", ByKey, `{"root":{"B":"5","b":{"y":"3","z":"2"},"item10":"1","item2":"4"}}`},
+		{"natural", NaturalOrder, `{"root":{"B":"5","b":{"y":"3","z":"2"},"item2":"4","item10":"1"}}`},
+		{
+			"custom",
+			func(a, b []byte) bool { return bytes.Compare(bytes.ToLower(a), bytes.ToLower(b)) > 0 },
+			`{"root":{"item2":"4","item10":"1","b":{"z":"2","y":"3"},"B":"5"}}`,
+		},
+	}
+	for _, test := range tests {
+		var buf strings.Builder
+		if err := (EncodeOptions{Less: test.less}).SerializeNode(node, &buf); err != nil {
+			t.Errorf("%s: SerializeNode() error: %v", test.name, err)
+		} else if got := buf.String(); got != test.want {
+			t.Errorf("%s: SerializeNode() = %s, want %s", test.name, got, test.want)
+		}
+	}
+	// The tree is not modified
+	if got := nodeString(node); got != tests[0].want {
+		t.Errorf("SerializeNode() modified the tree: %s", got)
+	}
+	// The Writer orders the top level keys with MultipleRoots
+	var buf strings.Builder
+	w := EncodeOptions{MultipleRoots: true, Less: NaturalOrder}.NewWriter(&buf)
+	if err := w.WriteNode(node); err != nil {
+		t.Fatalf("WriteNode() error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if got, want := buf.String(), `{"B":"5","b":{"y":"3","z":"2"},"item2":"4","item10":"1"}`; got != want {
+		t.Errorf("Writer wrote %s, want %s", got, want)
+	}
+}
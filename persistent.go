@@ -0,0 +1,178 @@
+package jsontree
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// PersistentNode is an immutable Node. With and Without return new trees
+// that share the subtrees they leave unchanged with the original, so keeping
+// older versions is cheap and readers need no locks. A nil *PersistentNode
+// is an empty tree for With.
+//
+// The methods of Node that would modify it do not: SetKey does nothing,
+// AddNode returns a node that is not added, and the Deserialize method of its
+// values fails, so that DeserializeNode fails rather than losing data. Use
+// Freeze to make a PersistentNode of a tree that was read.
+type PersistentNode struct {
+	key   []byte
+	value []byte
+	nodes []Node // all *PersistentNode
+}
+
+// Freeze returns a PersistentNode with the keys and values of node
+func Freeze(node Node) (*PersistentNode, error) {
+	if node == nil {
+		return nil, fmt.Errorf("node is nil")
+	}
+	n := &PersistentNode{key: node.Key()}
+	if nodes := node.Nodes(); len(nodes) > 0 {
+		n.nodes = make([]Node, len(nodes))
+		for i, child := range nodes {
+			if child == nil {
+				return nil, fmt.Errorf("invalid node: node.Nodes() contained nil")
+			}
+			c, err := Freeze(child)
+			if err != nil {
+				return nil, err
+			}
+			n.nodes[i] = c
+		}
+		return n, nil
+	}
+	value := node.Value()
+	if value == nil {
+		return nil, fmt.Errorf("invalid node: len(node.Nodes()) == 0 and node.Value() == nil")
+	}
+	raw, err := value.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	n.value = raw
+	return n, nil
+}
+
+func (n *PersistentNode) Key() []byte {
+	return n.key
+}
+
+// SetKey does nothing, as the node is immutable
+func (n *PersistentNode) SetKey(key []byte) {}
+
+func (n *PersistentNode) Value() Value {
+	return persistentValue(n.value)
+}
+
+// Nodes returns the children of n. The slice must not be modified.
+func (n *PersistentNode) Nodes() []Node {
+	return n.nodes[:len(n.nodes):len(n.nodes)]
+}
+
+// AddNode returns a new node with key, without adding it to n, which is
+// immutable
+func (n *PersistentNode) AddNode(key []byte) Node {
+	return &PersistentNode{key: key}
+}
+
+// With returns a tree where the node at path, below n, has the raw value.
+// Missing nodes are added after the existing ones. A node can not have both a
+// value and children, so it is an error if the node at path has children, or
+// if a node along it has a value. value can not be nil.
+func (n *PersistentNode) With(path [][]byte, value []byte) (*PersistentNode, error) {
+	if value == nil {
+		return nil, fmt.Errorf("value is nil")
+	}
+	return n.with(path, 0, value)
+}
+
+func (n *PersistentNode) with(path [][]byte, depth int, value []byte) (*PersistentNode, error) {
+	if n == nil {
+		n = new(PersistentNode)
+	}
+	if depth == len(path) {
+		if len(n.nodes) > 0 {
+			return nil, fmt.Errorf("%s has children", describePath(path))
+		}
+		c := *n
+		c.value = value
+		return &c, nil
+	}
+	if n.value != nil {
+		return nil, fmt.Errorf("%s has a value", describePath(path[:depth]))
+	}
+	c := *n
+	for i, child := range n.nodes {
+		if keyEqual(child.Key(), path[depth]) {
+			with, err := child.(*PersistentNode).with(path, depth+1, value)
+			if err != nil {
+				return nil, err
+			}
+			c.nodes = append([]Node(nil), n.nodes...)
+			c.nodes[i] = with
+			return &c, nil
+		}
+	}
+	child, err := (&PersistentNode{key: path[depth]}).with(path, depth+1, value)
+	if err != nil {
+		return nil, err
+	}
+	c.nodes = append(n.nodes[:len(n.nodes):len(n.nodes)], child)
+	return &c, nil
+}
+
+// Without returns a tree without the node at path, below n. It returns n
+// itself if there is no such node, or if path is empty. A node can not be left
+// without children nor value, so removing the only child of a node is an
+// error.
+func (n *PersistentNode) Without(path [][]byte) (*PersistentNode, error) {
+	return n.without(path, 0)
+}
+
+func (n *PersistentNode) without(path [][]byte, depth int) (*PersistentNode, error) {
+	if n == nil || depth == len(path) {
+		return n, nil
+	}
+	for i, child := range n.nodes {
+		if !keyEqual(child.Key(), path[depth]) {
+			continue
+		}
+		c := *n
+		if depth == len(path)-1 {
+			if len(n.nodes) == 1 {
+				return nil, fmt.Errorf("%s is the only child of its parent", describePath(path))
+			}
+			c.nodes = make([]Node, 0, len(n.nodes)-1)
+			c.nodes = append(append(c.nodes, n.nodes[:i]...), n.nodes[i+1:]...)
+			return &c, nil
+		}
+		without, err := child.(*PersistentNode).without(path, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if without == child {
+			return n, nil
+		}
+		c.nodes = append([]Node(nil), n.nodes...)
+		c.nodes[i] = without
+		return &c, nil
+	}
+	return n, nil
+}
+
+// describePath names the node at path for error messages
+func describePath(path [][]byte) string {
+	if len(path) == 0 {
+		return "the node"
+	}
+	return fmt.Sprintf("key \"%s\"", bytes.Join(path, []byte{'.'}))
+}
+
+type persistentValue []byte
+
+func (v persistentValue) Serialize() ([]byte, error) {
+	return v, nil
+}
+
+func (v persistentValue) Deserialize(b []byte) error {
+	return fmt.Errorf("the value of a PersistentNode can not be modified")
+}
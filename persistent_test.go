@@ -0,0 +1,126 @@
+package jsontree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPersistentNode(t *testing.T) {
+	doc := &DocumentNode{}
+	if err := DeserializeNode(doc, strings.NewReader(`{"root":{"a":"1","b":{"c":"2","d":"3"},"e":{"f":"4"}}}`)); err != nil {
+		t.Fatalf("DeserializeNode() error: %v", err)
+	}
+	v1, err := Freeze(doc)
+	if err != nil {
+		t.Fatalf("Freeze() error: %v", err)
+	}
+	v2, err := v1.With([][]byte{key("b"), key("c")}, []byte("20"))
+	if err != nil {
+		t.Fatalf("With() error: %v", err)
+	}
+	v3, err := v2.With([][]byte{key("g"), key("h")}, []byte("5"))
+	if err != nil {
+		t.Fatalf("With() error: %v", err)
+	}
+	if v3, err = v3.Without([][]byte{key("a")}); err != nil {
+		t.Fatalf("Without() error: %v", err)
+	}
+	v4, err := v3.Without([][]byte{key("b"), key("d")})
+	if err != nil {
+		t.Fatalf("Without() error: %v", err)
+	}
+
+	versions := []struct {
+		node *PersistentNode
+		want string
+	}{
+		{v1, `{"root":{"a":"1","b":{"c":"2","d":"3"},"e":{"f":"4"}}}`},
+		{v2, `{"root":{"a":"1","b":{"c":"20","d":"3"},"e":{"f":"4"}}}`},
+		{v3, `{"root":{"b":{"c":"20","d":"3"},"e":{"f":"4"},"g":{"h":"5"}}}`},
+		{v4, `{"root":{"b":{"c":"20"},"e":{"f":"4"},"g":{"h":"5"}}}`},
+	}
+	for i, version := range versions {
+		if got := nodeString(version.node); got != version.want {
+			t.Errorf("Version %d: Wrong tree\nWant %s\nGot  %s", i+1, version.want, got)
+		}
+	}
+
+	// Unchanged subtrees are shared
+	if getNode(v1, key("e")) != getNode(v4, key("e")) {
+		t.Errorf("Subtree e is not shared")
+	}
+	if getNode(v1, key("b")) == getNode(v2, key("b")) || getNode(v2, key("b")) != getNode(v3, key("b")) {
+		t.Errorf("Subtree b is not shared as expected")
+	}
+	for _, path := range [][][]byte{{key("missing")}, {key("b"), key("missing")}, nil} {
+		if got, err := v4.Without(path); got != v4 || err != nil {
+			t.Errorf("Without(%q) = %s, %v, want the same tree", path, nodeString(got), err)
+		}
+	}
+
+	// Writer.WriteNode reads it like any node
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.WriteNode(getNode(v4, key("g"))); err != nil {
+		t.Fatalf("WriteNode() error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if got, want := buf.String(), `{"g":{"h":"5"}}`; got != want {
+		t.Errorf("Wrong document\nWant %s\nGot  %s", want, got)
+	}
+}
+
+func TestPersistentNodeImmutable(t *testing.T) {
+	var empty *PersistentNode
+	n, err := empty.With([][]byte{key("a")}, []byte("1"))
+	if err != nil {
+		t.Fatalf("With() error: %v", err)
+	}
+	n.SetKey(key("root"))
+	if err := n.Value().Deserialize([]byte("x")); err == nil {
+		t.Errorf("Deserialize() returned no error")
+	}
+	n.AddNode(key("b"))
+	if n.Key() != nil || len(n.Nodes()) != 1 {
+		t.Errorf("The node was modified: %s", nodeString(n))
+	}
+	want := "the value of a PersistentNode can not be modified"
+	if err := DeserializeNode(n, strings.NewReader(`{"root":{"c":"3"}}`)); err == nil || err.Error() != want {
+		t.Errorf("Wrong error\nWant %s\nGot  %v", want, err)
+	}
+	if _, err := Freeze(&testNode{nilNodes: true}); err == nil {
+		t.Errorf("Freeze() returned no error for an invalid node")
+	}
+}
+
+func TestPersistentNodeErrors(t *testing.T) {
+	var empty *PersistentNode
+	n, err := empty.With([][]byte{key("a"), key("b")}, []byte("1"))
+	if err != nil {
+		t.Fatalf("With() error: %v", err)
+	}
+	n.key = key("root")
+	tests := []struct {
+		name string
+		fn   func() (*PersistentNode, error)
+		err  string
+	}{
+		{"nil value", func() (*PersistentNode, error) { return n.With([][]byte{key("c")}, nil) }, "value is nil"},
+		{"value of a parent", func() (*PersistentNode, error) { return n.With([][]byte{key("a")}, []byte("2")) }, `key "a" has children`},
+		{"value of the node", func() (*PersistentNode, error) { return n.With(nil, []byte("2")) }, "the node has children"},
+		{"child of a leaf", func() (*PersistentNode, error) { return n.With([][]byte{key("a"), key("b"), key("c")}, []byte("2")) }, `key "a.b" has a value`},
+		{"only child", func() (*PersistentNode, error) { return n.Without([][]byte{key("a"), key("b")}) }, `key "a.b" is the only child of its parent`},
+		{"only child of the node", func() (*PersistentNode, error) { return n.Without([][]byte{key("a")}) }, `key "a" is the only child of its parent`},
+	}
+	for _, test := range tests {
+		if got, err := test.fn(); err == nil || err.Error() != test.err || got != nil {
+			t.Errorf("%s: Wrong error\nWant %s\nGot  %v", test.name, test.err, err)
+		}
+	}
+	if got := nodeString(n); got != `{"root":{"a":{"b":"1"}}}` {
+		t.Errorf("The node was modified: %s", got)
+	}
+}
@@ -0,0 +1,165 @@
+package jsontree
+
+import (
+	"math"
+	"strings"
+)
+
+// Plural categories of the CLDR plural rules
+const (
+	PluralZero  = "zero"
+	PluralOne   = "one"
+	PluralTwo   = "two"
+	PluralFew   = "few"
+	PluralMany  = "many"
+	PluralOther = "other"
+)
+
+type pluralRule func(n float64, i int64, integer bool) string
+
+// pluralRules are the cardinal rules of common languages. Languages without
+// one only have the category "other".
+var pluralRules = map[string]pluralRule{}
+
+func init() {
+	oneOther := func(n float64, i int64, integer bool) string {
+		if integer && i == 1 {
+			return PluralOne
+		}
+		return PluralOther
+	}
+	zeroOneOther := func(n float64, i int64, integer bool) string {
+		if i == 0 || i == 1 {
+			return PluralOne
+		}
+		return PluralOther
+	}
+	slavic := func(n float64, i int64, integer bool) string {
+		switch {
+		case !integer:
+			return PluralOther
+		case i%10 == 1 && i%100 != 11:
+			return PluralOne
+		case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+			return PluralFew
+		}
+		return PluralMany
+	}
+	// millions adds to rule the category many of the Romance languages, for
+	// integers that are a multiple of a million
+	millions := func(rule pluralRule) pluralRule {
+		return func(n float64, i int64, integer bool) string {
+			if integer && i != 0 && i%1000000 == 0 {
+				return PluralMany
+			}
+			return rule(n, i, integer)
+		}
+	}
+	for _, lang := range []string{"en", "de", "nl", "sv", "da", "nb", "nn", "no", "fi", "et", "el", "hu", "tr", "bg"} {
+		pluralRules[lang] = oneOther
+	}
+	for _, lang := range []string{"it", "es", "ca"} {
+		pluralRules[lang] = millions(oneOther)
+	}
+	for _, lang := range []string{"fr", "pt"} {
+		pluralRules[lang] = millions(zeroOneOther)
+	}
+	for _, lang := range []string{"ru", "uk", "be"} {
+		pluralRules[lang] = slavic
+	}
+	pluralRules["pl"] = func(n float64, i int64, integer bool) string {
+		switch {
+		case !integer:
+			return PluralOther
+		case i == 1:
+			return PluralOne
+		case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+			return PluralFew
+		}
+		return PluralMany
+	}
+	czech := func(n float64, i int64, integer bool) string {
+		switch {
+		case !integer:
+			return PluralMany
+		case i == 1:
+			return PluralOne
+		case i >= 2 && i <= 4:
+			return PluralFew
+		}
+		return PluralOther
+	}
+	pluralRules["cs"], pluralRules["sk"] = czech, czech
+	pluralRules["ar"] = func(n float64, i int64, integer bool) string {
+		switch {
+		case !integer:
+			return PluralOther
+		case i == 0:
+			return PluralZero
+		case i == 1:
+			return PluralOne
+		case i == 2:
+			return PluralTwo
+		case i%100 >= 3 && i%100 <= 10:
+			return PluralFew
+		case i%100 >= 11:
+			return PluralMany
+		}
+		return PluralOther
+	}
+	pluralRules["he"] = func(n float64, i int64, integer bool) string {
+		switch {
+		case integer && i == 1:
+			return PluralOne
+		case integer && i == 2:
+			return PluralTwo
+		}
+		return PluralOther
+	}
+}
+
+// PluralCategory returns the plural category of n in the language of locale,
+// a BCP 47 tag like "en" or "pt-BR".
+func PluralCategory(locale string, n float64) string {
+	rule, ok := pluralRules[localeLanguage(locale)]
+	if !ok {
+		return PluralOther
+	}
+	n = math.Abs(n)
+	i := int64(n)
+	return rule(n, i, float64(i) == n)
+}
+
+// ordinalCategory returns the ordinal plural category of n, for
+// selectordinal. Only English has ordinal rules, other languages have "other".
+func ordinalCategory(locale string, n float64) string {
+	i := int64(math.Abs(n))
+	if localeLanguage(locale) != "en" || float64(i) != math.Abs(n) {
+		return PluralOther
+	}
+	switch {
+	case i%10 == 1 && i%100 != 11:
+		return PluralOne
+	case i%10 == 2 && i%100 != 12:
+		return PluralTwo
+	case i%10 == 3 && i%100 != 13:
+		return PluralFew
+	}
+	return PluralOther
+}
+
+// localeLanguage returns the lower case language of locale
+func localeLanguage(locale string) string {
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+	return strings.ToLower(locale)
+}
+
+func isPluralCategory(s string) bool {
+	switch s {
+	case PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther:
+		return true
+	}
+	return false
+}
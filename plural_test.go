@@ -0,0 +1,42 @@
+package jsontree
+
+import "testing"
+
+func TestPluralCategory(t *testing.T) {
+	tests := []struct {
+		locale string
+		n      []float64
+		want   []string
+	}{
+		{"en", []float64{0, 1, 2, 1.5, -1}, []string{"other", "one", "other", "other", "one"}},
+		{"pt_BR", []float64{0, 1, 2}, []string{"one", "one", "other"}},
+		{"fr", []float64{0, 1.5, 2, 1000000, 2000000, 1000001, 1500000.5}, []string{"one", "one", "other", "many", "many", "other", "other"}},
+		{"it", []float64{1, 1000, 1000000, 3000000}, []string{"one", "other", "many", "many"}},
+		{"es", []float64{1, 1000000}, []string{"one", "many"}},
+		{"RU", []float64{1, 3, 5, 11, 21, 22, 112, 0.5}, []string{"one", "few", "many", "many", "one", "few", "many", "other"}},
+		{"pl", []float64{1, 2, 5, 21, 22}, []string{"one", "few", "many", "many", "few"}},
+		{"cs", []float64{1, 3, 5, 1.5}, []string{"one", "few", "other", "many"}},
+		{"ar", []float64{0, 1, 2, 3, 11, 100, 102}, []string{"zero", "one", "two", "few", "many", "other", "other"}},
+		{"he", []float64{1, 2, 3}, []string{"one", "two", "other"}},
+		{"zh-Hant", []float64{1}, []string{"other"}},
+		{"", []float64{1}, []string{"other"}},
+	}
+	for _, test := range tests {
+		for i, n := range test.n {
+			if got := PluralCategory(test.locale, n); got != test.want[i] {
+				t.Errorf("PluralCategory(%q, %v) = %s, want %s", test.locale, n, got, test.want[i])
+			}
+		}
+	}
+}
+
+func TestOrdinalCategory(t *testing.T) {
+	for n, want := range map[float64]string{1: "one", 2: "two", 3: "few", 4: "other", 11: "other", 12: "other", 13: "other", 21: "one", 102: "two"} {
+		if got := ordinalCategory("en-GB", n); got != want {
+			t.Errorf("ordinalCategory(%v) = %s, want %s", n, got, want)
+		}
+	}
+	if got := ordinalCategory("de", 1); got != "other" {
+		t.Errorf("ordinalCategory(\"de\", 1) = %s, want other", got)
+	}
+}
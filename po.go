@@ -0,0 +1,595 @@
+package jsontree
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Every leaf of a tree is an entry of a gettext PO file. The path of the
+// parent of the leaf, joined with dots, is the msgctxt of the entry, the key
+// of the leaf its msgid and the value its msgstr. Leaves at the top level
+// have no msgctxt. Plural entries are parents, whose children "0", "1", ...
+// are the plural forms.
+//
+// What does not fit in a Node tree, like comments and flags, is kept in a
+// POMetadata alongside the tree.
+
+// POEntry is the metadata of a PO entry
+type POEntry struct {
+	Comments          []string // translator comments, "# "
+	ExtractedComments []string // "#."
+	References        []string // "#:"
+	Flags             []string // "#,", like fuzzy or c-format
+	Previous          []string // "#|", the previous msgctxt and msgid of fuzzy entries
+	MsgidPlural       string   // the msgid_plural of plural entries
+}
+
+func (e *POEntry) Fuzzy() bool {
+	for _, flag := range e.Flags {
+		if flag == "fuzzy" {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *POEntry) SetFuzzy(fuzzy bool) {
+	flags := e.Flags[:0:0]
+	for _, flag := range e.Flags {
+		if flag != "fuzzy" {
+			flags = append(flags, flag)
+		}
+	}
+	if fuzzy {
+		flags = append([]string{"fuzzy"}, flags...)
+	}
+	e.Flags = flags
+}
+
+func (e *POEntry) isEmpty() bool {
+	return len(e.Comments) == 0 && len(e.ExtractedComments) == 0 && len(e.References) == 0 &&
+		len(e.Flags) == 0 && len(e.Previous) == 0 && e.MsgidPlural == ""
+}
+
+// POMetadata is the metadata of a PO file. Header is the msgstr of its header
+// entry, whose own metadata is the entry of the empty path.
+type POMetadata struct {
+	Header  string
+	entries map[string]*POEntry
+}
+
+// Entry returns the metadata of the entry of the leaf at path, or nil
+func (m *POMetadata) Entry(path ...[]byte) *POEntry {
+	if m == nil {
+		return nil
+	}
+	return m.entries[pathString(path)]
+}
+
+func (m *POMetadata) SetEntry(path [][]byte, entry *POEntry) {
+	if m.entries == nil {
+		m.entries = make(map[string]*POEntry)
+	}
+	m.entries[pathString(path)] = entry
+}
+
+func DeserializePO(node Node, r io.Reader) (*POMetadata, error) {
+	return DecodeOptions{}.DeserializePO(node, r)
+}
+
+func SerializePO(node Node, meta *POMetadata, w io.Writer) error {
+	return EncodeOptions{}.SerializePO(node, meta, w)
+}
+
+func SerializePOT(node Node, meta *POMetadata, w io.Writer) error {
+	return EncodeOptions{}.SerializePOT(node, meta, w)
+}
+
+// DeserializePO reads a PO or POT file into node, returning its metadata.
+// Like DeserializeNode, the entries must have the same first node unless
+// opts.MultipleRoots is set. MaxBytes is the only limit that applies.
+// Obsolete entries are skipped, and an entry with the msgctxt and msgid of
+// another is an error.
+func (opts DecodeOptions) DeserializePO(node Node, r io.Reader) (*POMetadata, error) {
+	data, err := readAll(r, opts.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	p := &poReader{tree: treeBuilder{root: node, multi: opts.MultipleRoots}, meta: new(POMetadata)}
+	lines, offsets := splitLines(data)
+	for i, line := range lines {
+		p.pos = Position{Offset: offsets[i], Line: i + 1, Column: 1}
+		if err := p.readLine(bytes.TrimSpace(line)); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.flush(); err != nil {
+		return nil, err
+	}
+	return p.meta, nil
+}
+
+// SerializePO writes node as a PO file, with the comments, flags and header
+// of meta, which may be nil.
+func (opts EncodeOptions) SerializePO(node Node, meta *POMetadata, w io.Writer) error {
+	return serializePO(node, meta, w, &opts, false)
+}
+
+// SerializePOT writes node as a POT file: a PO file whose msgstrs are empty.
+func (opts EncodeOptions) SerializePOT(node Node, meta *POMetadata, w io.Writer) error {
+	return serializePO(node, meta, w, &opts, true)
+}
+
+type poReader struct {
+	tree  treeBuilder
+	meta  *POMetadata
+	pos   Position
+	entry *POEntry
+	ctxt  *string
+	id    *string
+	strs  map[int]*string // the msgstrs by plural form, -1 for a singular entry
+	last  *string         // the string continued by lines starting with '"'
+}
+
+func (p *poReader) readLine(line []byte) error {
+	if len(line) == 0 {
+		return p.flush()
+	}
+	if line[0] == '"' {
+		if p.last == nil {
+			return p.errorf("unexpected string")
+		}
+		s, err := p.readString(line)
+		if err != nil {
+			return err
+		}
+		*p.last += s
+		return nil
+	}
+	if line[0] == '#' {
+		return p.readComment(line)
+	}
+	i := bytes.IndexAny(line, " \t")
+	if i < 0 {
+		return p.errorf("expected a keyword and a string")
+	}
+	keyword := string(line[:i])
+	s, err := p.readString(bytes.TrimSpace(line[i:]))
+	if err != nil {
+		return err
+	}
+	if (keyword == "msgctxt" || keyword == "msgid") && p.strs != nil {
+		if err := p.flush(); err != nil {
+			return err
+		}
+	}
+	if p.entry == nil {
+		p.entry = new(POEntry)
+	}
+	switch {
+	case keyword == "msgctxt" && p.ctxt == nil && p.id == nil:
+		p.ctxt = &s
+	case keyword == "msgid" && p.id == nil:
+		p.id = &s
+	case keyword == "msgid_plural" && p.id != nil && p.strs == nil:
+		p.entry.MsgidPlural = s
+		p.last = &p.entry.MsgidPlural
+		return nil
+	case keyword == "msgstr" && p.id != nil && p.strs == nil && p.entry.MsgidPlural == "":
+		p.strs = map[int]*string{-1: &s}
+	case strings.HasPrefix(keyword, "msgstr[") && strings.HasSuffix(keyword, "]") && p.id != nil && p.entry.MsgidPlural != "":
+		n, err := strconv.Atoi(keyword[len("msgstr[") : len(keyword)-1])
+		if err != nil || n < 0 {
+			return p.errorf("invalid %s", keyword)
+		}
+		if p.strs == nil {
+			p.strs = make(map[int]*string)
+		}
+		if _, ok := p.strs[n]; ok {
+			return p.errorf("duplicate %s", keyword)
+		}
+		p.strs[n] = &s
+	default:
+		return p.errorf("unexpected %s", keyword)
+	}
+	p.last = &s
+	return nil
+}
+
+func (p *poReader) readComment(line []byte) error {
+	if p.strs != nil {
+		if err := p.flush(); err != nil {
+			return err
+		}
+	}
+	p.last = nil
+	if bytes.HasPrefix(line, []byte("#~")) {
+		p.entry = nil // an obsolete entry, and its comments, are dropped
+		return nil
+	}
+	if p.entry == nil {
+		p.entry = new(POEntry)
+	}
+	kind, text := byte(' '), ""
+	if len(line) > 1 {
+		kind, text = line[1], strings.TrimSpace(string(line[2:]))
+	}
+	switch kind {
+	case '.':
+		p.entry.ExtractedComments = append(p.entry.ExtractedComments, text)
+	case ':':
+		p.entry.References = append(p.entry.References, text)
+	case ',':
+		for _, flag := range strings.Split(text, ",") {
+			if flag = strings.TrimSpace(flag); flag != "" {
+				p.entry.Flags = append(p.entry.Flags, flag)
+			}
+		}
+	case '|':
+		p.entry.Previous = append(p.entry.Previous, text)
+	default:
+		p.entry.Comments = append(p.entry.Comments, strings.TrimPrefix(string(line[1:]), " "))
+	}
+	return nil
+}
+
+// flush adds the entry that has been read to the tree
+func (p *poReader) flush() error {
+	entry, ctxt, id, strs := p.entry, p.ctxt, p.id, p.strs
+	p.entry, p.ctxt, p.id, p.strs, p.last = nil, nil, nil, nil, nil
+	if id == nil {
+		if ctxt == nil && strs == nil {
+			return nil // no entry, or comments without one
+		}
+		return p.errorf("expected msgid")
+	}
+	if strs == nil {
+		return p.errorf("expected msgstr")
+	}
+	if *id == "" && ctxt == nil {
+		if strs[-1] == nil {
+			return p.errorf("invalid header entry")
+		}
+		p.meta.Header = *strs[-1]
+		if !entry.isEmpty() {
+			p.meta.SetEntry(nil, entry)
+		}
+		return nil
+	}
+	var path [][]byte
+	if ctxt != nil && *ctxt != "" {
+		for _, key := range strings.Split(*ctxt, ".") {
+			path = append(path, appendEscaped(nil, []byte(key)))
+		}
+	}
+	path = append(path, appendEscaped(nil, []byte(*id)))
+	if !entry.isEmpty() {
+		p.meta.SetEntry(path, entry)
+	}
+	if entry.MsgidPlural == "" {
+		return p.setValue(path, *strs[-1])
+	}
+	for n := 0; n < len(strs); n++ {
+		s, ok := strs[n]
+		if !ok {
+			return p.errorf("missing msgstr[%d]", n)
+		}
+		if err := p.setValue(append(path[:len(path):len(path)], []byte(strconv.Itoa(n))), *s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *poReader) setValue(path [][]byte, text string) error {
+	if p.tree.leaves[pathString(path)] {
+		return &SyntaxError{Path: path, Pos: p.pos, Err: fmt.Errorf("duplicate message definition")}
+	}
+	node, err := p.tree.leaf(path)
+	if err != nil {
+		return &SyntaxError{Path: path, Pos: p.pos, Err: err}
+	}
+	return node.Value().Deserialize(appendEscaped(nil, []byte(text)))
+}
+
+// readString reads a quoted string, the only content of s
+func (p *poReader) readString(s []byte) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", p.errorf("expected a quoted string")
+	}
+	text, err := unescapePO(s[1 : len(s)-1])
+	if err != nil {
+		return "", p.errorf("%v", err)
+	}
+	return string(text), nil
+}
+
+func (p *poReader) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Pos: p.pos, Err: fmt.Errorf(format, args...)}
+}
+
+// unescapePO returns the text of the contents of a C string
+func unescapePO(raw []byte) ([]byte, error) {
+	if bytes.IndexByte(raw, '\\') < 0 {
+		if bytes.IndexByte(raw, '"') >= 0 {
+			return nil, fmt.Errorf("unescaped '\"'")
+		}
+		return raw, nil
+	}
+	text := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		switch raw[i] {
+		case '"':
+			return nil, fmt.Errorf("unescaped '\"'")
+		case '\\':
+		default:
+			text = append(text, raw[i])
+			continue
+		}
+		i++
+		if i == len(raw) {
+			return nil, fmt.Errorf("invalid escape at end of string")
+		}
+		switch esc := raw[i]; esc {
+		case 'n':
+			text = append(text, '\n')
+		case 't':
+			text = append(text, '\t')
+		case 'r':
+			text = append(text, '\r')
+		case 'a':
+			text = append(text, '\a')
+		case 'b':
+			text = append(text, '\b')
+		case 'f':
+			text = append(text, '\f')
+		case 'v':
+			text = append(text, '\v')
+		case '"', '\\', '\'', '?':
+			text = append(text, esc)
+		case 'x':
+			j := i + 1
+			for j < len(raw) && j < i+3 && isHexDigit(raw[j]) {
+				j++
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("invalid escape '\\x'")
+			}
+			b, _ := strconv.ParseUint(string(raw[i+1:j]), 16, 8)
+			text = append(text, byte(b))
+			i = j - 1
+		default:
+			if esc < '0' || esc > '7' {
+				return nil, fmt.Errorf("invalid escape '\\%c'", esc)
+			}
+			j := i
+			for j < len(raw) && j < i+3 && raw[j] >= '0' && raw[j] <= '7' {
+				j++
+			}
+			b, err := strconv.ParseUint(string(raw[i:j]), 8, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid escape '\\%s'", raw[i:j])
+			}
+			text = append(text, byte(b))
+			i = j - 1
+		}
+	}
+	return text, nil
+}
+
+func isHexDigit(b byte) bool {
+	return isDigit(b) || (b >= 'a' && b <= 'f') || (b >= 'A' && b <= 'F')
+}
+
+func serializePO(node Node, meta *POMetadata, w io.Writer, opts *EncodeOptions, template bool) error {
+	if node == nil {
+		return fmt.Errorf("node is nil")
+	}
+	p := &poWriter{w: bufio.NewWriter(w), meta: meta, opts: opts, template: template}
+	header := "Content-Type: text/plain; charset=UTF-8\n"
+	if meta != nil && meta.Header != "" {
+		header = meta.Header
+	}
+	p.writeEntry(meta.Entry(), nil, "", []string{header})
+	nodes := []Node{node}
+	if opts.MultipleRoots {
+		nodes = node.Nodes()
+	}
+	if err := p.writeNodes(nodes, nil); err != nil {
+		return err
+	}
+	return p.w.Flush()
+}
+
+type poWriter struct {
+	w        *bufio.Writer
+	meta     *POMetadata
+	opts     *EncodeOptions
+	template bool
+	written  bool
+}
+
+func (p *poWriter) writeNodes(nodes []Node, path [][]byte) error {
+	nodes, err := p.opts.order(nodes)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if node == nil {
+			return fmt.Errorf("invalid node: node.Nodes() contained nil")
+		}
+		if err := p.writeNode(node, append(path[:len(path):len(path)], node.Key())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *poWriter) writeNode(node Node, path [][]byte) error {
+	entry := p.meta.Entry(path...)
+	nodes := node.Nodes()
+	if len(nodes) > 0 && (entry == nil || entry.MsgidPlural == "") {
+		key, err := unescapeString(node.Key())
+		if err != nil {
+			return err
+		}
+		if bytes.IndexByte(key, '.') >= 0 {
+			return fmt.Errorf("key \"%s\" of a parent can not be written to a PO file", node.Key())
+		}
+		return p.writeNodes(nodes, path)
+	}
+	var ctxt []string
+	for _, key := range path {
+		text, err := unescapeString(key)
+		if err != nil {
+			return err
+		}
+		ctxt = append(ctxt, string(text))
+	}
+	id := ctxt[len(ctxt)-1]
+	if id == "" {
+		return fmt.Errorf("empty keys can not be written to a PO file")
+	}
+	var strs []string
+	if len(nodes) > 0 {
+		// The plural forms are the children "0", "1", ...
+		forms := make([]Node, len(nodes))
+		for _, child := range nodes {
+			n, err := strconv.Atoi(string(child.Key()))
+			if err != nil || n < 0 || n >= len(nodes) || forms[n] != nil {
+				return fmt.Errorf("invalid plural form \"%s\" of \"%s\"", child.Key(), node.Key())
+			}
+			forms[n] = child
+		}
+		for _, form := range forms {
+			s, err := leafText(form)
+			if err != nil {
+				return err
+			}
+			strs = append(strs, s)
+		}
+	} else {
+		s, err := leafText(node)
+		if err != nil {
+			return err
+		}
+		strs = []string{s}
+	}
+	if p.template {
+		for i := range strs {
+			strs[i] = ""
+		}
+	}
+	p.writeEntry(entry, ctxt[:len(ctxt)-1], id, strs)
+	return nil
+}
+
+// leafText returns the text of the value of node
+func leafText(node Node) (string, error) {
+	if len(node.Nodes()) > 0 {
+		return "", fmt.Errorf("node \"%s\" is not a leaf", node.Key())
+	}
+	value := node.Value()
+	if value == nil {
+		return "", fmt.Errorf("invalid node: len(node.Nodes()) == 0 and node.Value() == nil")
+	}
+	raw, err := value.Serialize()
+	if err != nil {
+		return "", err
+	}
+	text, err := unescapeString(raw)
+	return string(text), err
+}
+
+func (p *poWriter) writeEntry(entry *POEntry, ctxt []string, id string, strs []string) {
+	if p.written {
+		p.w.WriteByte('\n')
+	}
+	p.written = true
+	if entry != nil {
+		for _, comment := range entry.Comments {
+			p.writeComment("#", comment)
+		}
+		for _, comment := range entry.ExtractedComments {
+			p.writeComment("#.", comment)
+		}
+		for _, ref := range entry.References {
+			p.writeComment("#:", ref)
+		}
+		if len(entry.Flags) > 0 {
+			p.writeComment("#,", strings.Join(entry.Flags, ", "))
+		}
+		for _, prev := range entry.Previous {
+			p.writeComment("#|", prev)
+		}
+	}
+	if len(ctxt) > 0 {
+		p.writeString("msgctxt", strings.Join(ctxt, "."))
+	}
+	p.writeString("msgid", id)
+	if entry != nil && entry.MsgidPlural != "" {
+		p.writeString("msgid_plural", entry.MsgidPlural)
+		for i, s := range strs {
+			p.writeString("msgstr["+strconv.Itoa(i)+"]", s)
+		}
+		return
+	}
+	p.writeString("msgstr", strs[0])
+}
+
+func (p *poWriter) writeComment(marker, text string) {
+	p.w.WriteString(marker)
+	if text != "" {
+		p.w.WriteByte(' ')
+		p.w.WriteString(text)
+	}
+	p.w.WriteByte('\n')
+}
+
+// writeString writes a keyword and its string. Strings with line breaks are
+// written a line per line.
+func (p *poWriter) writeString(keyword, s string) {
+	p.w.WriteString(keyword)
+	p.w.WriteByte(' ')
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > 1 {
+		p.w.WriteString("\"\"\n")
+	}
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+	for _, line := range lines {
+		p.w.Write(appendPOEscaped([]byte{'"'}, line))
+		p.w.WriteString("\"\n")
+	}
+}
+
+// appendPOEscaped appends s to dst, escaped as the contents of a C string
+func appendPOEscaped(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch b := s[i]; b {
+		case '"', '\\':
+			dst = append(dst, '\\', b)
+		case '\n':
+			dst = append(dst, '\\', 'n')
+		case '\t':
+			dst = append(dst, '\\', 't')
+		case '\r':
+			dst = append(dst, '\\', 'r')
+		default:
+			if b < 0x20 || b == 0x7f {
+				dst = append(dst, fmt.Sprintf("\\%03o", b)...)
+			} else {
+				dst = append(dst, b)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,148 @@
+package jsontree
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testPO = `# Translation of the app
+msgid ""
+msgstr ""
+"Content-Type: text/plain; charset=UTF-8\n"
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+# Shown on the home page
+#. The greeting
+#: home.go:12
+#, fuzzy, c-format
+#| msgid "Hi"
+msgctxt "en"
+msgid "greeting"
+msgstr "Hello \"%s\""
+
+msgctxt "en.menu"
+msgid "open"
+msgstr ""
+"Open\n"
+"file"
+
+msgctxt "en"
+msgid "files"
+msgid_plural "files"
+msgstr[0] "%d file"
+msgstr[1] "%d files"
+
+#~ msgctxt "en"
+#~ msgid "old"
+#~ msgstr "Old"
+`
+
+func TestDeserializePO(t *testing.T) {
+	node := &testNode{}
+	meta, err := DeserializePO(node, strings.NewReader(testPO))
+	if err != nil {
+		t.Fatalf("DeserializePO() error: %v", err)
+	}
+	want := `{"en":{"greeting":"Hello \"%s\"","menu":{"open":"Open\nfile"},"files":{"0":"%d file","1":"%d files"}}}`
+	if got := nodeString(node); got != want {
+		t.Errorf("Wrong tree\nWant %s\nGot  %s", want, got)
+	}
+	if want := "Content-Type: text/plain; charset=UTF-8\nPlural-Forms: nplurals=2; plural=(n != 1);\n"; meta.Header != want {
+		t.Errorf("Wrong header\nWant %q\nGot  %q", want, meta.Header)
+	}
+	if got, want := meta.Entry(), (&POEntry{Comments: []string{"Translation of the app"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Wrong header entry\nWant %+v\nGot  %+v", want, got)
+	}
+	entry := meta.Entry(key("en"), key("greeting"))
+	wantEntry := &POEntry{
+		Comments:          []string{"Shown on the home page"},
+		ExtractedComments: []string{"The greeting"},
+		References:        []string{"home.go:12"},
+		Flags:             []string{"fuzzy", "c-format"},
+		Previous:          []string{`msgid "Hi"`},
+	}
+	if !reflect.DeepEqual(entry, wantEntry) {
+		t.Errorf("Wrong entry\nWant %+v\nGot  %+v", wantEntry, entry)
+	}
+	if !entry.Fuzzy() {
+		t.Errorf("Entry is not fuzzy")
+	}
+	if entry := meta.Entry(key("en"), key("files")); entry == nil || entry.MsgidPlural != "files" {
+		t.Errorf("Wrong plural entry %+v", entry)
+	}
+	if entry := meta.Entry(key("en"), key("menu"), key("open")); entry != nil {
+		t.Errorf("Entry without metadata has an entry: %+v", entry)
+	}
+
+	// Writing the tree back gives the same file, without the obsolete entry
+	var buf bytes.Buffer
+	if err := SerializePO(node, meta, &buf); err != nil {
+		t.Fatalf("SerializePO() error: %v", err)
+	}
+	want = testPO[:strings.Index(testPO, "\n#~")]
+	if got := buf.String(); got != want {
+		t.Errorf("Wrong PO\nWant %s\nGot  %s", want, got)
+	}
+}
+
+func TestDeserializePOErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		err  string
+	}{
+		{"missing msgstr", "msgid \"a\"\n\n", `2:1: expected msgstr (at "")`},
+		{"string without keyword", "\"a\"\n", `1:1: unexpected string (at "")`},
+		{"unknown keyword", "msgid \"a\"\nmsgfoo \"b\"\n", `2:1: unexpected msgfoo (at "")`},
+		{"unquoted", "msgid a\n", `1:1: expected a quoted string (at "")`},
+		{"bad escape", "msgid \"\\q\"\n", `1:1: invalid escape '\q' (at "")`},
+		{"missing plural form", "msgid \"a\"\nmsgid_plural \"b\"\nmsgstr[1] \"c\"\n", `3:1: missing msgstr[0] (at "")`},
+		{"duplicate message", "msgctxt \"a\"\nmsgid \"x\"\nmsgstr \"1\"\n\nmsgctxt \"a\"\nmsgid \"x\"\nmsgstr \"2\"\n", `7:1: duplicate message definition (at "a.x")`},
+		{"duplicate plural message", "msgid \"x\"\nmsgid_plural \"xs\"\nmsgstr[0] \"1\"\n\nmsgid \"x\"\nmsgid_plural \"xs\"\nmsgstr[0] \"2\"\n", `7:1: duplicate message definition (at "x.0")`},
+		{"several roots", "msgctxt \"a\"\nmsgid \"x\"\nmsgstr \"\"\n\nmsgctxt \"b\"\nmsgid \"x\"\nmsgstr \"\"\n", `7:1: expected 1 root node (at "b.x")`},
+	}
+	for _, test := range tests {
+		_, err := DeserializePO(&testNode{}, strings.NewReader(test.in))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%s: Wrong error\nWant %s\nGot  %v", test.name, test.err, err)
+		}
+	}
+}
+
+func TestSerializePOT(t *testing.T) {
+	node := &testNode{}
+	json := `{"a":"x","b":{"c":"y\\\u0001"}}`
+	if err := (DecodeOptions{MultipleRoots: true}).DeserializeNode(node, strings.NewReader(json)); err != nil {
+		t.Fatalf("DeserializeNode() error: %v", err)
+	}
+	meta := new(POMetadata)
+	entry := &POEntry{Flags: []string{"c-format"}}
+	entry.SetFuzzy(true)
+	meta.SetEntry([][]byte{key("a")}, entry)
+
+	var buf bytes.Buffer
+	if err := (EncodeOptions{MultipleRoots: true}).SerializePO(node, meta, &buf); err != nil {
+		t.Fatalf("SerializePO() error: %v", err)
+	}
+	want := "msgid \"\"\nmsgstr \"Content-Type: text/plain; charset=UTF-8\\n\"\n\n#, fuzzy, c-format\nmsgid \"a\"\nmsgstr \"x\"\n\nmsgctxt \"b\"\nmsgid \"c\"\nmsgstr \"y\\\\\\001\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Wrong PO\nWant %s\nGot  %s", want, got)
+	}
+	back := &testNode{}
+	if _, err := (DecodeOptions{MultipleRoots: true}).DeserializePO(back, &buf); err != nil {
+		t.Fatalf("DeserializePO() error: %v", err)
+	} else if !nodeEqual(node, back) {
+		t.Errorf("Round trip changed the tree\nWant %s\nGot  %s", nodeString(node), nodeString(back))
+	}
+
+	buf.Reset()
+	if err := (EncodeOptions{MultipleRoots: true}).SerializePOT(node, nil, &buf); err != nil {
+		t.Fatalf("SerializePOT() error: %v", err)
+	}
+	want = "msgid \"\"\nmsgstr \"Content-Type: text/plain; charset=UTF-8\\n\"\n\nmsgid \"a\"\nmsgstr \"\"\n\nmsgctxt \"b\"\nmsgid \"c\"\nmsgstr \"\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Wrong POT\nWant %s\nGot  %s", want, got)
+	}
+}
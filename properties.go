@@ -0,0 +1,317 @@
+package jsontree
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Keys of Java .properties files are paths, with dots separating the keys of
+// the nodes. Comments are kept by CommentNodes: a comment is the one of the
+// first key following it, unless there is a blank line in between.
+// Non-ASCII characters are written as \uXXXX escapes, which all versions of
+// Java read.
+
+func DeserializeProperties(node Node, r io.Reader) error {
+	return DecodeOptions{}.DeserializeProperties(node, r)
+}
+
+func SerializeProperties(node Node, w io.Writer) error {
+	return EncodeOptions{}.SerializeProperties(node, w)
+}
+
+// DeserializeProperties reads a .properties file into node. Like
+// DeserializeNode, the keys must have the same first node unless
+// opts.MultipleRoots is set. Keys found more than once are handled by
+// opts.Duplicates. MaxBytes is the only limit that applies.
+func (opts DecodeOptions) DeserializeProperties(node Node, r io.Reader) error {
+	data, err := readAll(r, opts.MaxBytes)
+	if err != nil {
+		return err
+	}
+	tree := &treeBuilder{root: node, multi: opts.MultipleRoots}
+	lines, offsets := splitLines(data)
+	var comment []byte
+	for i := 0; i < len(lines); i++ {
+		pos := Position{Offset: offsets[i], Line: i + 1, Column: 1}
+		line := bytes.TrimLeft(lines[i], " \t\f")
+		if len(line) == 0 {
+			comment = nil
+			continue
+		}
+		if line[0] == '#' || line[0] == '!' {
+			comment = appendCommentLine(comment, line[1:])
+			continue
+		}
+		// A line ending with an odd number of backslashes continues on the next
+		for isContinued(line) {
+			line = line[: len(line)-1 : len(line)-1]
+			if i+1 < len(lines) {
+				i++
+				line = append(line, bytes.TrimLeft(lines[i], " \t\f")...)
+			}
+		}
+		rawKey, rawValue := splitProperty(line)
+		key, err := unescapeProperty(rawKey)
+		if err != nil {
+			return &SyntaxError{Pos: pos, Err: err}
+		}
+		value, err := unescapeProperty(rawValue)
+		if err != nil {
+			return &SyntaxError{Pos: pos, Err: err}
+		}
+		var path [][]byte
+		for _, k := range bytes.Split(key, []byte{'.'}) {
+			if len(k) == 0 {
+				return &SyntaxError{Pos: pos, Err: fmt.Errorf("invalid key \"%s\"", key)}
+			}
+			path = append(path, appendEscaped(nil, k))
+		}
+		if skip, err := tree.duplicate(path, pos, &opts); err != nil || skip {
+			if err != nil {
+				return err
+			}
+			comment = nil
+			continue
+		}
+		n, err := tree.leaf(path)
+		if err != nil {
+			return &SyntaxError{Path: path, Pos: pos, Err: err}
+		}
+		if err := n.Value().Deserialize(appendEscaped(nil, value)); err != nil {
+			return err
+		}
+		if cn, ok := n.(CommentNode); ok && comment != nil {
+			cn.SetComment(comment)
+		}
+		comment = nil
+	}
+	return nil
+}
+
+// SerializeProperties writes node as a .properties file, with a line for
+// every leaf. Keys containing dots can not be written.
+func (opts EncodeOptions) SerializeProperties(node Node, w io.Writer) error {
+	if node == nil {
+		return fmt.Errorf("node is nil")
+	}
+	p := &propertiesWriter{w: bufio.NewWriter(w), opts: &opts}
+	nodes := []Node{node}
+	if opts.MultipleRoots {
+		nodes = node.Nodes()
+	}
+	if err := p.writeNodes(nodes, nil); err != nil {
+		return err
+	}
+	return p.w.Flush()
+}
+
+// splitLines returns the lines of data, without their line breaks, and
+// their offsets
+func splitLines(data []byte) (lines [][]byte, offsets []int) {
+	offset := 0
+	for len(data) > 0 {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line = data[:i]
+			data = data[i+1:]
+		} else {
+			data = nil
+		}
+		lines = append(lines, bytes.TrimSuffix(line, []byte{'\r'}))
+		offsets = append(offsets, offset)
+		offset += len(line) + 1
+	}
+	return lines, offsets
+}
+
+// appendCommentLine adds a line to a comment, dropping the space that
+// usually follows the comment marker
+func appendCommentLine(comment, line []byte) []byte {
+	if comment != nil {
+		comment = append(comment, '\n')
+	} else {
+		comment = []byte{}
+	}
+	if len(line) > 0 && line[0] == ' ' {
+		line = line[1:]
+	}
+	return append(comment, bytes.TrimRight(line, " \t")...)
+}
+
+func isContinued(line []byte) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitProperty splits a logical line into its escaped key and value. The
+// key ends at the first unescaped '=', ':' or whitespace.
+func splitProperty(line []byte) (key, value []byte) {
+	i := 0
+	for ; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if line[i] == '=' || line[i] == ':' || isPropertySpace(line[i]) {
+			break
+		}
+	}
+	key, value = line[:i], line[i:]
+	value = bytes.TrimLeft(value, " \t\f")
+	if len(value) > 0 && (value[0] == '=' || value[0] == ':') {
+		value = bytes.TrimLeft(value[1:], " \t\f")
+	}
+	return key, value
+}
+
+func isPropertySpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\f'
+}
+
+func unescapeProperty(raw []byte) ([]byte, error) {
+	if bytes.IndexByte(raw, '\\') < 0 {
+		return raw, nil
+	}
+	text := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' {
+			text = append(text, raw[i])
+			continue
+		}
+		i++
+		if i == len(raw) {
+			break
+		}
+		switch esc := raw[i]; esc {
+		case 't':
+			text = append(text, '\t')
+		case 'n':
+			text = append(text, '\n')
+		case 'r':
+			text = append(text, '\r')
+		case 'f':
+			text = append(text, '\f')
+		case 'u':
+			r, n, err := readUnicodeEscape(raw[i+1:])
+			if err != nil {
+				return nil, fmt.Errorf("malformed \\uxxxx escape: %v", err)
+			}
+			i += n
+			text = utf8.AppendRune(text, r)
+		default:
+			text = append(text, esc)
+		}
+	}
+	return text, nil
+}
+
+// appendPropertyEscaped appends text to dst, escaped as a key or a value
+func appendPropertyEscaped(dst, text []byte, isKey bool) []byte {
+	const hex = "0123456789ABCDEF"
+	for i, r := range string(text) {
+		switch {
+		case r == '\\' || r == '=' || r == ':' || r == '#' || r == '!':
+			dst = append(dst, '\\', byte(r))
+		case r == ' ' && (isKey || i == 0):
+			dst = append(dst, '\\', ' ')
+		case r == '\t':
+			dst = append(dst, '\\', 't')
+		case r == '\n':
+			dst = append(dst, '\\', 'n')
+		case r == '\r':
+			dst = append(dst, '\\', 'r')
+		case r == '\f':
+			dst = append(dst, '\\', 'f')
+		case r < 0x20 || r > 0x7e:
+			units := []rune{r}
+			if r >= 0x10000 {
+				r1, r2 := utf16.EncodeRune(r)
+				units = []rune{r1, r2}
+			}
+			for _, u := range units {
+				dst = append(dst, '\\', 'u', hex[u>>12&0xf], hex[u>>8&0xf], hex[u>>4&0xf], hex[u&0xf])
+			}
+		default:
+			dst = append(dst, byte(r))
+		}
+	}
+	return dst
+}
+
+type propertiesWriter struct {
+	w        *bufio.Writer
+	opts     *EncodeOptions
+	comments [][]byte // comments of parents, written before their first leaf
+}
+
+func (p *propertiesWriter) writeNodes(nodes []Node, path [][]byte) error {
+	nodes, err := p.opts.order(nodes)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if node == nil {
+			return fmt.Errorf("invalid node: node.Nodes() contained nil")
+		}
+		if err := p.writeNode(node, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *propertiesWriter) writeNode(node Node, path [][]byte) error {
+	key, err := unescapeString(node.Key())
+	if err != nil {
+		return err
+	}
+	if len(key) == 0 || bytes.IndexByte(key, '.') >= 0 {
+		return fmt.Errorf("key \"%s\" can not be written to a .properties file", node.Key())
+	}
+	path = append(path[:len(path):len(path)], key)
+	if cn, ok := node.(CommentNode); ok && len(cn.Comment()) > 0 {
+		p.comments = append(p.comments, cn.Comment())
+	}
+	if nodes := node.Nodes(); len(nodes) > 0 {
+		return p.writeNodes(nodes, path)
+	}
+	value := node.Value()
+	if value == nil {
+		return fmt.Errorf("invalid node: len(node.Nodes()) == 0 and node.Value() == nil")
+	}
+	raw, err := value.Serialize()
+	if err != nil {
+		return err
+	}
+	text, err := unescapeString(raw)
+	if err != nil {
+		return err
+	}
+	for _, comment := range p.comments {
+		writeCommentLines(p.w, "#", comment)
+	}
+	p.comments = p.comments[:0]
+	p.w.Write(appendPropertyEscaped(nil, bytes.Join(path, []byte{'.'}), true))
+	p.w.WriteByte('=')
+	p.w.Write(appendPropertyEscaped(nil, text, false))
+	return p.w.WriteByte('\n')
+}
+
+// writeCommentLines writes every line of comment, after marker
+func writeCommentLines(w *bufio.Writer, marker string, comment []byte) {
+	for _, line := range bytes.Split(comment, []byte{'\n'}) {
+		w.WriteString(marker)
+		if len(line) > 0 {
+			w.WriteByte(' ')
+			w.Write(line)
+		}
+		w.WriteByte('\n')
+	}
+}
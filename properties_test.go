@@ -0,0 +1,189 @@
+package jsontree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDeserializeProperties(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		multi bool
+		dups  DuplicatePolicy
+		want  string
+		err   string
+	}{
+		{
+			name: "dotted keys",
+			in:   "en.greeting=Hello\nen.menu.open = Open file\nen.menu.close:Close\n",
+			want: `{"en":{"greeting":"Hello","menu":{"open":"Open file","close":"Close"}}}`,
+		},
+		{
+			name: "separators and comments",
+			in:   "# comment\n! other comment\n  en.a  Hello  world \nen.b\nen.c=\\ x=y:z\n",
+			want: `{"en":{"a":"Hello  world ","b":"","c":" x=y:z"}}`,
+		},
+		{
+			name: "escapes",
+			in:   "en.a\\ b\\=c=tab\\tnl\\nq\\\"\\\\\nen.u=\\u00e9\\uD83D\\uDE00\\z\n",
+			want: `{"en":{"a b=c":"tab\tnl\nq\"\\","u":"é😀z"}}`,
+		},
+		{
+			name: "continuation lines",
+			in:   "en.a=one \\\n    two \\\n\tthree\nen.b=x\\\\\nen.c=y\\",
+			want: `{"en":{"a":"one two three","b":"x\\","c":"y"}}`,
+		},
+		{
+			name:  "multiple roots",
+			in:    "a=x\r\nb.c=y\r\n",
+			multi: true,
+			want:  `{"a":"x","b":{"c":"y"}}`,
+		},
+		{
+			name: "several roots",
+			in:   "a.b=x\nc.d=y\n",
+			err:  `2:1: expected 1 root node (at "c.d")`,
+		},
+		{
+			name: "value and parent",
+			in:   "en.a=x\nen.a.b=y\n",
+			err:  `2:1: key "en.a" is both a value and a parent (at "en.a.b")`,
+		},
+		{
+			name: "parent and value",
+			in:   "en.a.b=y\nen.a=x\n",
+			err:  `2:1: key "en.a" is both a value and a parent (at "en.a")`,
+		},
+		{
+			name: "duplicate key, last wins",
+			in:   "en.a=x\nen.b=y\nen.a=z\n",
+			want: `{"en":{"a":"z","b":"y"}}`,
+		},
+		{
+			name: "duplicate key, first wins",
+			in:   "en.a=x\nen.b=y\nen.a=z\n",
+			dups: DuplicateFirstWins,
+			want: `{"en":{"a":"x","b":"y"}}`,
+		},
+		{
+			name: "duplicate key",
+			in:   "en.a=x\nen.b=y\nen.a=z\n",
+			dups: DuplicateError,
+			err:  `duplicate key "en.a" at 3:1, first defined at 1:1`,
+		},
+		{
+			name: "empty key",
+			in:   "en..a=x\n",
+			err:  `1:1: invalid key "en..a" (at "")`,
+		},
+		{
+			name: "bad escape",
+			in:   "en.a=\\u12\n",
+			err:  `1:1: malformed \uxxxx escape: invalid unicode escape (at "")`,
+		},
+	}
+	for _, test := range tests {
+		node := &testNode{}
+		err := DecodeOptions{MultipleRoots: test.multi, Duplicates: test.dups}.DeserializeProperties(node, strings.NewReader(test.in))
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: Wrong error\nWant %s\nGot  %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %v", test.name, err)
+			continue
+		}
+		var buf bytes.Buffer
+		if err := (EncodeOptions{MultipleRoots: test.multi}).SerializeNode(node, &buf); err != nil {
+			t.Errorf("%s: SerializeNode() error: %v", test.name, err)
+		} else if got := buf.String(); got != test.want {
+			t.Errorf("%s: Wrong tree\nWant %s\nGot  %s", test.name, test.want, got)
+		}
+	}
+}
+
+func TestSerializeProperties(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  string
+		multi bool
+		want  string
+		err   string
+	}{
+		{
+			name: "nested",
+			json: `{"en":{"greeting":"Hello, world","menu":{"open":"Open"}}}`,
+			want: "en.greeting=Hello, world\nen.menu.open=Open\n",
+		},
+		{
+			name: "escaped",
+			json: `{"en":{"a b":" x=y\n\té😀","c#":"!"}}`,
+			want: "en.a\\ b=\\ x\\=y\\n\\t\\u00E9\\uD83D\\uDE00\nen.c\\#=\\!\n",
+		},
+		{
+			name:  "multiple roots",
+			json:  `{"a":"x","b":{"c":"y"}}`,
+			multi: true,
+			want:  "a=x\nb.c=y\n",
+		},
+		{
+			name: "dotted key",
+			json: `{"en":{"a.b":"x"}}`,
+			err:  `key "a.b" can not be written to a .properties file`,
+		},
+	}
+	for _, test := range tests {
+		node := &testNode{}
+		if err := (DecodeOptions{MultipleRoots: test.multi}).DeserializeNode(node, strings.NewReader(test.json)); err != nil {
+			t.Fatalf("%s: DeserializeNode() error: %v", test.name, err)
+		}
+		var buf bytes.Buffer
+		err := (EncodeOptions{MultipleRoots: test.multi}).SerializeProperties(node, &buf)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: Wrong error\nWant %s\nGot  %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: SerializeProperties() error: %v", test.name, err)
+			continue
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("%s: Wrong .properties\nWant %q\nGot  %q", test.name, test.want, got)
+		}
+		back := &testNode{}
+		if err := (DecodeOptions{MultipleRoots: test.multi}).DeserializeProperties(back, &buf); err != nil {
+			t.Errorf("%s: DeserializeProperties() error: %v", test.name, err)
+		} else if !nodeEqual(node, back) {
+			t.Errorf("%s: Round trip changed the tree\nWant %s\nGot  %s", test.name, nodeString(node), nodeString(back))
+		}
+	}
+}
+
+func TestPropertiesComments(t *testing.T) {
+	in := "# Greetings\n#\n# for the home page\nen.hello=Hello\n\n# dropped\n\nen.bye=Bye\n"
+	node := new(DocumentNode)
+	if err := DeserializeProperties(node, strings.NewReader(in)); err != nil {
+		t.Fatalf("DeserializeProperties() error: %v", err)
+	}
+	hello := getNode(node, key("hello")).(CommentNode)
+	if got, want := string(hello.Comment()), "Greetings\n\nfor the home page"; got != want {
+		t.Errorf("Wrong comment\nWant %q\nGot  %q", want, got)
+	}
+	if got := getNode(node, key("bye")).(CommentNode).Comment(); got != nil {
+		t.Errorf("Comment separated by a blank line was kept: %q", got)
+	}
+	var buf bytes.Buffer
+	if err := SerializeProperties(node, &buf); err != nil {
+		t.Fatalf("SerializeProperties() error: %v", err)
+	}
+	want := "# Greetings\n#\n# for the home page\nen.hello=Hello\nen.bye=Bye\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Wrong .properties\nWant %q\nGot  %q", want, got)
+	}
+}
@@ -0,0 +1,134 @@
+package jsontree
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// SyntaxError is a syntax error found in a document. Path is the path of the
+// key being read when the error was found, Pos the position of the
+// offending byte.
+type SyntaxError struct {
+	Path [][]byte
+	Pos  Position
+	Err  error
+}
+
+func (err *SyntaxError) Error() string {
+	return fmt.Sprintf("%s: %v (at \"%s\")", err.Pos, err.Err, bytes.Join(err.Path, []byte{'.'}))
+}
+
+func (err *SyntaxError) Unwrap() error {
+	return err.Err
+}
+
+// MultiError is returned when DecodeOptions.RecoverErrors is set and the
+// document has errors. It holds every error found, in document order.
+type MultiError []*SyntaxError
+
+func (errs MultiError) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (errs MultiError) Unwrap() []error {
+	unwrapped := make([]error, len(errs))
+	for i, err := range errs {
+		unwrapped[i] = err
+	}
+	return unwrapped
+}
+
+// recover records the syntax error in p.err and makes the parser
+// resynchronize at the next ',' or '}' of the object being read. It returns
+// false if the error can not be recovered from.
+func (p *parser) recover() bool {
+	if _, ok := p.err.(*DeserializeError); !ok {
+		return false
+	}
+	p.errors = append(p.errors, &SyntaxError{
+		Path: append([][]byte(nil), p.path...),
+		Pos:  p.errPos,
+		Err:  p.err,
+	})
+	// A value read before the error is still returned by Scan, so the
+	// resynchronization is left to the next state
+	p.err = nil
+	p.next = (*parser).readResync
+	return true
+}
+
+// abort ends the scan with err, and returns it along with the errors
+// recovered from so far
+func (p *parser) abort(err error) error {
+	p.err = err
+	return p.Err()
+}
+
+func (p *parser) readResync() (readFn, error) {
+	sync, err := p.resync()
+	if err != nil {
+		return nil, err
+	}
+	// Restore the path to what it would be before the ',' or '}' of an entry
+	// of the current object
+	if len(p.path) > p.depth {
+		p.path = p.path[:p.depth]
+	}
+	if len(p.path) < p.depth {
+		// The key of the entry was never read. Ignore anything below it.
+		p.skip = len(p.path) + 1
+		for len(p.path) < p.depth {
+			p.path.Push(nil)
+		}
+	}
+	if sync == ',' {
+		p.path.Pop()
+		return (*parser).readQuotedKey, nil
+	}
+	return p.closeBracket()
+}
+
+// resync reads up to and including the next ',' or '}' at the current
+// nesting level, skipping over strings and nested objects. If the byte that
+// caused the error was consumed, resync starts from it.
+func (p *parser) resync() (byte, error) {
+	nesting := 0
+	b, read := p.errors[len(p.errors)-1].Err.(*DeserializeError).Got, p.errRead
+	for {
+		if !read {
+			var err error
+			if b, err = p.r.ReadByte(); err != nil {
+				return 0, err
+			}
+		}
+		read = false
+		switch b {
+		case '"':
+			if _, err := p.r.readString(b, 0); err != nil {
+				return 0, err
+			}
+		case '\'':
+			if p.opts.Dialect == DialectJSON5 {
+				if _, err := p.r.readString(b, 0); err != nil {
+					return 0, err
+				}
+			}
+		case '{':
+			nesting++
+		case '}':
+			if nesting == 0 {
+				return b, nil
+			}
+			nesting--
+		case ',':
+			if nesting == 0 {
+				return b, nil
+			}
+		}
+	}
+}
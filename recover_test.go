@@ -0,0 +1,140 @@
+package jsontree
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRecoverErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want *testNode
+		errs []string
+	}{
+		{
+			name: "bad value",
+			in:   `{"r":{"a":?,"b":"v2"}}`,
+			want: &testNode{key: key("r"), nodes: []*testNode{
+				{key: key("b"), value: val("v2")},
+			}},
+			errs: []string{`1:11: Read '?', expected '{' or '"' (at "r.a")`},
+		},
+		{
+			name: "several errors",
+			in:   `{"r":{"a"?"v1","b":"v2","c":{"x":"v3"?},"d":"v4",?:"v5","e":"v6"}}`,
+			want: &testNode{key: key("r"), nodes: []*testNode{
+				{key: key("b"), value: val("v2")},
+				{key: key("c"), nodes: []*testNode{{key: key("x"), value: val("v3")}}},
+				{key: key("d"), value: val("v4")},
+				{key: key("e"), value: val("v6")},
+			}},
+			errs: []string{
+				`1:10: Read '?', expected ':' (at "r.a")`,
+				`1:38: Read '?', expected '}' or ',' (at "r.c.x")`,
+				`1:50: Read '?', expected '"' (at "r")`,
+			},
+		},
+		{
+			name: "nested object is skipped",
+			in:   `{"r":{"a":"v1"?{"x":"1","y":"2"},"b":"v2"}}`,
+			want: &testNode{key: key("r"), nodes: []*testNode{
+				{key: key("a"), value: val("v1")},
+				{key: key("b"), value: val("v2")},
+			}},
+			errs: []string{`1:15: Read '?', expected '}' or ',' (at "r.a")`},
+		},
+		{
+			name: "error consumes closing bracket",
+			in:   `{"r":{"a":"v1",}}`,
+			want: &testNode{key: key("r"), nodes: []*testNode{
+				{key: key("a"), value: val("v1")},
+			}},
+			errs: []string{`1:16: Read '}', expected '"' (at "r")`},
+		},
+		{
+			name: "missing colon",
+			in:   `{"root":{"a":"1","b" "2","c":"3"}}`,
+			want: &testNode{key: key("root"), nodes: []*testNode{
+				{key: key("a"), value: val("1")},
+				{key: key("c"), value: val("3")},
+			}},
+			errs: []string{`1:22: Read '"', expected ':' (at "root.b")`},
+		},
+		{
+			name: "unexpected object",
+			in:   `{"root":{"a":"1","b"{"x":"2"},"c":"3"}}`,
+			want: &testNode{key: key("root"), nodes: []*testNode{
+				{key: key("a"), value: val("1")},
+				{key: key("c"), value: val("3")},
+			}},
+			errs: []string{`1:21: Read '{', expected ':' (at "root.b")`},
+		},
+		{
+			name: "unexpected end of input",
+			in:   `{"r":{"a":?,"b":"v2","c":`,
+			want: &testNode{key: key("r"), nodes: []*testNode{
+				{key: key("b"), value: val("v2")},
+			}},
+			errs: []string{
+				`1:11: Read '?', expected '{' or '"' (at "r.a")`,
+				`1:26: reader returned io.EOF before expected (at "r.c")`,
+			},
+		},
+	}
+	opts := DecodeOptions{RecoverErrors: true}
+	for _, test := range tests {
+		for _, fromBytes := range []bool{false, true} {
+			node := new(testNode)
+			var err error
+			if fromBytes {
+				err = opts.DeserializeBytes(node, []byte(test.in))
+			} else {
+				err = opts.DeserializeNode(node, strings.NewReader(test.in))
+			}
+			var errs MultiError
+			if !errors.As(err, &errs) {
+				t.Errorf("%s: DeserializeNode() returned %T (%v), want MultiError", test.name, err, err)
+				continue
+			}
+			got := make([]string, len(errs))
+			for i, err := range errs {
+				got[i] = err.Error()
+			}
+			if strings.Join(got, "\n") != strings.Join(test.errs, "\n") {
+				t.Errorf("%s (bytes: %v): Wrong errors\nWant %s\nGot  %s", test.name, fromBytes, strings.Join(test.errs, "\n     "), strings.Join(got, "\n     "))
+			}
+			if !nodeEqual(node, test.want) {
+				t.Errorf("%s (bytes: %v): Node was not as expected\nWant %v\nGot  %v", test.name, fromBytes, nodeString(test.want), nodeString(node))
+			}
+		}
+	}
+}
+
+func TestRecoverErrorsFatal(t *testing.T) {
+	// Errors other than syntax errors are not recovered from
+	opts := DecodeOptions{RecoverErrors: true, MaxKeyLen: 2}
+	err := opts.DeserializeNode(new(testNode), strings.NewReader(`{"r":{"a":?,"bbb":"v","c":"v"}}`))
+	var errs MultiError
+	var limitErr *LimitError
+	if !errors.As(err, &errs) || len(errs) != 2 || !errors.As(err, &limitErr) {
+		t.Errorf("Wrong error\nWant syntax error followed by LimitError\nGot  %v", err)
+	}
+	// Nor are errors of values, which are returned with those recovered from
+	node := &testNode{key: key("r"), nodes: []*testNode{{key: key("b"), value: &testValue{deserializeErr: fmt.Errorf("bad value")}}}}
+	err = DecodeOptions{RecoverErrors: true}.DeserializeNode(node, strings.NewReader(`{"r":{"a":?,"b":"v"}}`))
+	if !errors.As(err, &errs) || len(errs) != 2 || errs[1].Err.Error() != "bad value" {
+		t.Errorf("Wrong error\nWant syntax error followed by the error of the value\nGot  %v", err)
+	}
+	// Without errors, nothing changes
+	if err := opts.DeserializeNode(new(testNode), strings.NewReader(`{"r":"v"}`)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	// A valid document with errors turned off is not affected
+	wantErr := fmt.Errorf("Read '?', expected '{' or '\"'")
+	if err := DeserializeNode(new(testNode), strings.NewReader(`{"r":{"a":?}}`)); !errEqual(wantErr, err) {
+		t.Errorf("Wrong error\nWant %v\nGot  %v", wantErr, err)
+	}
+}
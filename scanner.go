@@ -0,0 +1,105 @@
+package jsontree
+
+import (
+	"io"
+)
+
+// DeserializeBytes is like DeserializeNode, but reads the document from data
+// without copying it. The key and value slices passed to node.SetKey,
+// node.AddNode and Value.Deserialize alias data, so data must not be modified
+// for as long as the node keeps references to them.
+func DeserializeBytes(node Node, data []byte) error {
+	return DecodeOptions{}.DeserializeBytes(node, data)
+}
+
+// Scanner reads the leaf values of a document held in memory, one at a time.
+//
+// The slices returned by Path and Value alias the input and are valid for as
+// long as the input is not modified. The [][]byte returned by Path is reused
+// by the Scanner and is only valid until the next call to Scan.
+type Scanner struct {
+	p *parser
+}
+
+func NewScanner(data []byte) *Scanner {
+	return DecodeOptions{}.NewScanner(data)
+}
+
+func (s *Scanner) Scan() bool {
+	return s.p.Scan()
+}
+
+func (s *Scanner) Path() [][]byte {
+	return s.p.path
+}
+
+func (s *Scanner) Value() []byte {
+	return s.p.value
+}
+
+func (s *Scanner) Err() error {
+	return s.p.Err()
+}
+
+type sliceSource struct {
+	data []byte
+	pos  int
+	// Positions are computed on demand. at caches the last one computed.
+	at Position
+}
+
+func newSliceSource(data []byte) *sliceSource {
+	return &sliceSource{data: data}
+}
+
+func (s *sliceSource) ReadByte() (byte, error) {
+	if s.pos >= len(s.data) {
+		return 0, io.EOF
+	}
+	b := s.data[s.pos]
+	s.pos++
+	return b, nil
+}
+
+func (s *sliceSource) Peek(n int) ([]byte, error) {
+	if end := s.pos + n; end <= len(s.data) {
+		return s.data[s.pos:end], nil
+	}
+	return s.data[s.pos:], io.EOF
+}
+
+func (s *sliceSource) position() Position {
+	return s.positionAt(s.pos)
+}
+
+func (s *sliceSource) lastPosition() Position {
+	return s.positionAt(s.pos - 1)
+}
+
+func (s *sliceSource) positionAt(offset int) Position {
+	if s.at.Line == 0 || offset < s.at.Offset {
+		s.at = Position{Line: 1, Column: 1}
+	}
+	for _, b := range s.data[s.at.Offset:offset] {
+		s.at.advance(b)
+	}
+	return s.at
+}
+
+func (s *sliceSource) readString(quote byte, max int) ([]byte, error) {
+	start := s.pos
+	for i := start; i < len(s.data); i++ {
+		if max > 0 && i-start > max {
+			return nil, errTooLong
+		}
+		switch s.data[i] {
+		case '\\':
+			i++ // skip the escaped byte
+		case quote:
+			s.pos = i + 1
+			return s.data[start:i:i], nil
+		}
+	}
+	s.pos = len(s.data)
+	return nil, io.EOF
+}
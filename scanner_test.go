@@ -0,0 +1,113 @@
+package jsontree
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDeserializeBytes(t *testing.T) {
+	tests := []struct {
+		in   string
+		want *testNode
+		err  error
+	}{
+		{
+			in:   `{"a":"b"}`,
+			want: &testNode{key: key("a"), value: val("b")},
+		},
+		{
+			in: `{"root":{"a":"v1","b":{"b1":{"b11":"v3"},"b2":""}}}`,
+			want: &testNode{key: key("root"), nodes: []*testNode{
+				{key: key("a"), value: val("v1")},
+				{key: key("b"), nodes: []*testNode{
+					{key: key("b1"), nodes: []*testNode{
+						{key: key("b11"), value: val("v3")},
+					}},
+					{key: key("b2"), value: val("")},
+				}},
+			}},
+		},
+		{
+			in: `{"ro\"ot":{"{a}":"\"hello\"","b}":"\\backslash\nnewline"}}`,
+			want: &testNode{key: key(`ro\"ot`), nodes: []*testNode{
+				{key: key(`{a}`), value: val(`\"hello\"`)},
+				{key: key(`b}`), value: val(`\\backslash\nnewline`)},
+			}},
+		},
+		{
+			in:  `{"a":"b"},`,
+			err: fmt.Errorf("expected end of input. Got ','"),
+		},
+		{
+			in:  `{"a":"b"`,
+			err: fmt.Errorf("reader returned io.EOF before expected"),
+		},
+		{
+			in:  `{"a\"`,
+			err: fmt.Errorf("reader returned io.EOF before expected"),
+		},
+		{
+			in:  `{"a":"b","c":"d"}`,
+			err: fmt.Errorf("invalid json. Expected 1 root node"),
+		},
+		{
+			in:  `{"a":?}`,
+			err: &DeserializeError{Got: '?', Want: []byte{'{', '"'}},
+		},
+	}
+	for _, test := range tests {
+		node := new(testNode)
+		err := DeserializeBytes(node, []byte(test.in))
+		if !errEqual(test.err, err) {
+			t.Errorf("%s\nWrong error.\nWant %v\nGot  %v", test.in, test.err, err)
+			continue
+		}
+		if test.err == nil && !nodeEqual(node, test.want) {
+			t.Errorf("%s: Node was not as expected\nWant %v\nGot  %v", test.in, nodeString(test.want), nodeString(node))
+		}
+	}
+}
+
+func TestScanner(t *testing.T) {
+	data := []byte(`{"root":{"a":"v1","b":{"c":"v2"}}}`)
+	want := []struct {
+		path  string
+		value string
+	}{
+		{"root/a", "v1"},
+		{"root/b/c", "v2"},
+	}
+	s := NewScanner(data)
+	i := 0
+	for ; s.Scan(); i++ {
+		if i >= len(want) {
+			t.Fatalf("Scan() returned more values than expected")
+		}
+		path := ""
+		for j, k := range s.Path() {
+			if j > 0 {
+				path += "/"
+			}
+			path += string(k)
+		}
+		if path != want[i].path || string(s.Value()) != want[i].value {
+			t.Errorf("Scan() #%d = %s:%s, want %s:%s", i, path, s.Value(), want[i].path, want[i].value)
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	if i != len(want) {
+		t.Errorf("Scan() returned %d values, want %d", i, len(want))
+	}
+
+	// The value slices alias the input
+	s = NewScanner(data)
+	if !s.Scan() {
+		t.Fatalf("Scan() returned false: %v", s.Err())
+	}
+	s.Value()[0] = 'X'
+	if data[14] != 'X' {
+		t.Errorf("Value() does not alias the input")
+	}
+}
@@ -0,0 +1,348 @@
+package jsontree
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is the subset of JSON Schema that applies to trees of nodes. A
+// node with children is an object, and a leaf a string. A leaf also has the
+// type integer, number or boolean if its text is a JSON integer, number or
+// boolean, so that "8080" is valid against {"type": "integer"}.
+//
+// The keywords are type, required, properties, additionalProperties, pattern,
+// enum, minLength and maxLength. Other keywords are ignored. False is the
+// schema false, that no node is valid against.
+type Schema struct {
+	Type                 []string
+	Required             []string
+	Properties           map[string]*Schema
+	AdditionalProperties *Schema // nil allows any additional property
+	Pattern              string
+	Enum                 []string
+	MinLength            *int
+	MaxLength            *int
+	False                bool
+}
+
+// ParseSchema parses a JSON Schema document
+func ParseSchema(data []byte) (*Schema, error) {
+	s := new(Schema)
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	switch string(bytes.TrimSpace(data)) {
+	case "true":
+		*s = Schema{}
+		return nil
+	case "false":
+		*s = Schema{False: true}
+		return nil
+	}
+	var raw struct {
+		Type                 json.RawMessage    `json:"type"`
+		Required             []string           `json:"required"`
+		Properties           map[string]*Schema `json:"properties"`
+		AdditionalProperties *Schema            `json:"additionalProperties"`
+		Pattern              string             `json:"pattern"`
+		Enum                 []json.RawMessage  `json:"enum"`
+		MinLength            *int               `json:"minLength"`
+		MaxLength            *int               `json:"maxLength"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*s = Schema{
+		Required:             raw.Required,
+		Properties:           raw.Properties,
+		AdditionalProperties: raw.AdditionalProperties,
+		Pattern:              raw.Pattern,
+		MinLength:            raw.MinLength,
+		MaxLength:            raw.MaxLength,
+	}
+	if len(raw.Type) > 0 {
+		var typ string
+		if err := json.Unmarshal(raw.Type, &typ); err == nil {
+			s.Type = []string{typ}
+		} else if err := json.Unmarshal(raw.Type, &s.Type); err != nil {
+			return fmt.Errorf("type must be a string or an array of strings")
+		}
+		for _, typ := range s.Type {
+			switch typ {
+			case "object", "string", "integer", "number", "boolean":
+			default:
+				return fmt.Errorf("unsupported type \"%s\"", typ)
+			}
+		}
+	}
+	for _, v := range raw.Enum {
+		text := string(v)
+		if len(v) > 0 && v[0] == '"' {
+			if err := json.Unmarshal(v, &text); err != nil {
+				return err
+			}
+		} else if !jsonNumber.Match(v) && text != "true" && text != "false" {
+			return fmt.Errorf("enum values must be strings, numbers or booleans")
+		}
+		s.Enum = append(s.Enum, text)
+	}
+	if _, err := regexp.Compile(s.Pattern); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ValidationError is a node that is not valid against a schema. Pointer is
+// the JSON Pointer (RFC 6901) of the node, relative to the validated node.
+type ValidationError struct {
+	Pointer string
+	Err     error
+}
+
+func (err *ValidationError) Error() string {
+	return fmt.Sprintf("%v (at \"%s\")", err.Err, err.Pointer)
+}
+
+func (err *ValidationError) Unwrap() error {
+	return err.Err
+}
+
+// ValidationErrors holds every violation of a schema, in the order of the
+// nodes
+type ValidationErrors []*ValidationError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (errs ValidationErrors) Unwrap() []error {
+	unwrapped := make([]error, len(errs))
+	for i, err := range errs {
+		unwrapped[i] = err
+	}
+	return unwrapped
+}
+
+// Validate validates node against the schema, and returns the violations in
+// a ValidationErrors. The schema describes the value of node, not its key: for
+// a document with a single root, the value of the root. A node without a key
+// and without children is an empty object, like the top level of a document
+// read with MultipleRoots.
+func (s *Schema) Validate(node Node) error {
+	if node == nil {
+		return fmt.Errorf("node is nil")
+	}
+	v := &validator{patterns: make(map[string]*regexp.Regexp)}
+	var err error
+	if len(node.Nodes()) > 0 || node.Key() == nil {
+		err = v.validateObject(s, node.Nodes(), "")
+	} else {
+		err = v.validate(s, node, "")
+	}
+	if err != nil {
+		return err
+	}
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+type validator struct {
+	patterns map[string]*regexp.Regexp
+	errs     ValidationErrors
+}
+
+func (v *validator) add(pointer string, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ValidationError{Pointer: pointer, Err: fmt.Errorf(format, args...)})
+}
+
+func (v *validator) validate(s *Schema, node Node, pointer string) error {
+	if nodes := node.Nodes(); len(nodes) > 0 {
+		return v.validateObject(s, nodes, pointer)
+	}
+	if s.False {
+		v.add(pointer, "no value is allowed")
+		return nil
+	}
+	value := node.Value()
+	if value == nil {
+		return fmt.Errorf("invalid node: len(node.Nodes()) == 0 and node.Value() == nil")
+	}
+	raw, err := value.Serialize()
+	if err != nil {
+		return err
+	}
+	b, err := unescapeString(raw)
+	if err != nil {
+		return err
+	}
+	text := string(b)
+	if len(s.Type) > 0 && !hasLeafType(s.Type, text) {
+		v.add(pointer, "expected %s, got string", strings.Join(s.Type, " or "))
+	}
+	if s.Pattern != "" {
+		re, ok := v.patterns[s.Pattern]
+		if !ok {
+			if re, err = regexp.Compile(s.Pattern); err != nil {
+				return err
+			}
+			v.patterns[s.Pattern] = re
+		}
+		if !re.MatchString(text) {
+			v.add(pointer, "\"%s\" does not match pattern \"%s\"", text, s.Pattern)
+		}
+	}
+	if len(s.Enum) > 0 && !containsString(s.Enum, text) {
+		v.add(pointer, "\"%s\" is not one of the enum values", text)
+	}
+	n := utf8.RuneCountInString(text)
+	if s.MinLength != nil && n < *s.MinLength {
+		v.add(pointer, "length %d is less than minLength %d", n, *s.MinLength)
+	}
+	if s.MaxLength != nil && n > *s.MaxLength {
+		v.add(pointer, "length %d is greater than maxLength %d", n, *s.MaxLength)
+	}
+	return nil
+}
+
+func (v *validator) validateObject(s *Schema, nodes []Node, pointer string) error {
+	if s.False {
+		v.add(pointer, "no value is allowed")
+		return nil
+	}
+	if len(s.Type) > 0 && !containsString(s.Type, "object") {
+		v.add(pointer, "expected %s, got object", strings.Join(s.Type, " or "))
+	}
+	if len(s.Enum) > 0 {
+		v.add(pointer, "an object is not one of the enum values")
+	}
+	names := make([]string, len(nodes))
+	for i, node := range nodes {
+		if node == nil {
+			return fmt.Errorf("invalid node: node.Nodes() contained nil")
+		}
+		name, err := unescapeString(node.Key())
+		if err != nil {
+			return err
+		}
+		names[i] = string(name)
+	}
+	for _, required := range s.Required {
+		if !containsString(names, required) {
+			v.add(pointer, "missing required property \"%s\"", required)
+		}
+	}
+	for i, node := range nodes {
+		p := pointer + "/" + escapePointer(names[i])
+		child, ok := s.Properties[names[i]]
+		if !ok {
+			child = s.AdditionalProperties
+			if child != nil && child.False {
+				v.add(p, "additional property \"%s\" is not allowed", names[i])
+				continue
+			}
+		}
+		if child == nil {
+			continue
+		}
+		if err := v.validate(child, node, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	jsonNumber  = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+	jsonInteger = regexp.MustCompile(`^-?(0|[1-9][0-9]*)$`)
+)
+
+// hasLeafType reports whether a leaf with text has one of types
+func hasLeafType(types []string, text string) bool {
+	for _, typ := range types {
+		switch typ {
+		case "string":
+			return true
+		case "integer":
+			if jsonInteger.MatchString(text) {
+				return true
+			}
+		case "number":
+			if jsonNumber.MatchString(text) {
+				return true
+			}
+		case "boolean":
+			if text == "true" || text == "false" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func escapePointer(name string) string {
+	return strings.Replace(strings.Replace(name, "~", "~0", -1), "/", "~1", -1)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON writes s as a JSON Schema. The enum values are written as
+// numbers or booleans when they have one of the types of s, and s does not
+// have the type string.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	if s.False {
+		return []byte("false"), nil
+	}
+	var raw struct {
+		Type                 interface{}        `json:"type,omitempty"`
+		Required             []string           `json:"required,omitempty"`
+		Properties           map[string]*Schema `json:"properties,omitempty"`
+		AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+		Pattern              string             `json:"pattern,omitempty"`
+		Enum                 []json.RawMessage  `json:"enum,omitempty"`
+		MinLength            *int               `json:"minLength,omitempty"`
+		MaxLength            *int               `json:"maxLength,omitempty"`
+	}
+	switch len(s.Type) {
+	case 0:
+	case 1:
+		raw.Type = s.Type[0]
+	default:
+		raw.Type = s.Type
+	}
+	raw.Required, raw.Properties, raw.AdditionalProperties = s.Required, s.Properties, s.AdditionalProperties
+	raw.Pattern, raw.MinLength, raw.MaxLength = s.Pattern, s.MinLength, s.MaxLength
+	literal := len(s.Type) > 0 && !containsString(s.Type, "string")
+	for _, text := range s.Enum {
+		if literal && hasLeafType(s.Type, text) {
+			raw.Enum = append(raw.Enum, json.RawMessage(text))
+			continue
+		}
+		b, err := json.Marshal(text)
+		if err != nil {
+			return nil, err
+		}
+		raw.Enum = append(raw.Enum, b)
+	}
+	return json.Marshal(raw)
+}
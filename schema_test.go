@@ -0,0 +1,192 @@
+package jsontree
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "required": ["server", "mode"],
+  "additionalProperties": false,
+  "properties": {
+    "server": {
+      "type": "object",
+      "required": ["host", "port"],
+      "properties": {
+        "host": {"type": "string", "minLength": 1, "maxLength": 8},
+        "port": {"type": "integer"},
+        "tls": {"type": ["boolean", "object"]}
+      },
+      "additionalProperties": {"pattern": "^[a-z]+$"}
+    },
+    "mode": {"enum": ["dev", "prod", 1, true]},
+    "a/b~c": true
+  }
+}`
+
+func TestSchemaValidate(t *testing.T) {
+	schema, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("ParseSchema() error: %v", err)
+	}
+	tests := []struct {
+		name string
+		in   string
+		err  string
+	}{
+		{
+			name: "valid",
+			in:   `{"server":{"host":"example","port":"8080","tls":"true","name":"web"},"mode":"prod","a/b~c":{"x":"y"}}`,
+		},
+		{
+			name: "enum values that are not strings",
+			in:   `{"server":{"host":"h","port":"-1","tls":{"cert":"c"}},"mode":"1"}`,
+		},
+		{
+			name: "violations",
+			in:   `{"server":{"host":"much too long","port":"80.5","tls":"yes","name":"Web","x":{"y":"z"}},"mode":{"a":"b"},"extra":"1","a/b~c":"1"}`,
+			err: `length 13 is greater than maxLength 8 (at "/server/host")
+expected integer, got string (at "/server/port")
+expected boolean or object, got string (at "/server/tls")
+"Web" does not match pattern "^[a-z]+$" (at "/server/name")
+an object is not one of the enum values (at "/mode")
+additional property "extra" is not allowed (at "/extra")`,
+		},
+		{
+			name: "missing",
+			in:   `{"server":{"host":""},"other":"1"}`,
+			err: `missing required property "mode" (at "")
+missing required property "port" (at "/server")
+length 0 is less than minLength 1 (at "/server/host")
+additional property "other" is not allowed (at "/other")`,
+		},
+		{
+			name: "enum",
+			in:   `{"mode":"test"}`,
+			err: `missing required property "server" (at "")
+"test" is not one of the enum values (at "/mode")`,
+		},
+	}
+	for _, test := range tests {
+		node := &testNode{}
+		if err := (DecodeOptions{MultipleRoots: true}).DeserializeNode(node, strings.NewReader(test.in)); err != nil {
+			t.Fatalf("%s: DeserializeNode() error: %v", test.name, err)
+		}
+		err := schema.Validate(node)
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("%s: Validate() error: %v", test.name, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%s: Wrong error\nWant %s\nGot  %v", test.name, test.err, err)
+		}
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Errorf("%s: errors.As() found no ValidationError", test.name)
+		}
+	}
+}
+
+func TestSchemaOptions(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{"required":["a"],"properties":{"a":{"pattern":"^x"}}}`))
+	if err != nil {
+		t.Fatalf("ParseSchema() error: %v", err)
+	}
+	node := &testNode{}
+	err = DecodeOptions{Schema: schema}.DeserializeNode(node, strings.NewReader(`{"root":{"a":"y"}}`))
+	if want := `"y" does not match pattern "^x" (at "/a")`; err == nil || err.Error() != want {
+		t.Errorf("Wrong error\nWant %s\nGot  %v", want, err)
+	}
+	if got, want := nodeString(node), `{"root":{"a":"y"}}`; got != want {
+		t.Errorf("Wrong tree\nWant %s\nGot  %s", want, got)
+	}
+	// The options validate the tree as Validate does
+	if got := schema.Validate(node); got == nil || got.Error() != err.Error() {
+		t.Errorf("Validate() = %v, want %v", got, err)
+	}
+	valid := &testNode{}
+	if err := (DecodeOptions{Schema: schema}).DeserializeNode(valid, strings.NewReader(`{"root":{"a":"x"}}`)); err != nil {
+		t.Errorf("DeserializeNode() error: %v", err)
+	}
+	if err := schema.Validate(valid); err != nil {
+		t.Errorf("Validate() error: %v", err)
+	}
+	multi := &testNode{}
+	err = DecodeOptions{Schema: schema, MultipleRoots: true}.DeserializeNode(multi, strings.NewReader(`{"a":"x1","b":"2"}`))
+	if err != nil {
+		t.Errorf("DeserializeNode() error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	invalid := &testNode{key: key("root"), nodes: []*testNode{{key: key("b"), value: val("1")}}}
+	err = EncodeOptions{Schema: schema}.SerializeNode(invalid, &buf)
+	if want := `missing required property "a" (at "")`; err == nil || err.Error() != want {
+		t.Errorf("Wrong error\nWant %s\nGot  %v", want, err)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("Invalid node written: %s", buf.String())
+	}
+	w := EncodeOptions{Schema: schema}.NewWriter(&buf)
+	if err := w.WriteNode(valid); err != nil {
+		t.Errorf("WriteNode() error: %v", err)
+	}
+	if err := w.WriteNode(invalid); err == nil {
+		t.Errorf("WriteNode() returned no error for an invalid node")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if got, want := buf.String(), `{"root":{"a":"x"}}`; got != want {
+		t.Errorf("Wrong document\nWant %s\nGot  %s", want, got)
+	}
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		err string
+	}{
+		{`{"type":"array"}`, `unsupported type "array"`},
+		{`{"type":3}`, "type must be a string or an array of strings"},
+		{`{"enum":[null]}`, "enum values must be strings, numbers or booleans"},
+		{`{"properties":{"a":{"pattern":"("}}}`, "error parsing regexp: missing closing ): `(`"},
+		{`{"minLength":"1"}`, "json: cannot unmarshal string into Go struct field .minLength of type int"},
+	}
+	for _, test := range tests {
+		if _, err := ParseSchema([]byte(test.in)); err == nil || err.Error() != test.err {
+			t.Errorf("%s: Wrong error\nWant %s\nGot  %v", test.in, test.err, err)
+		}
+	}
+	if schema, err := ParseSchema([]byte(`false`)); err != nil || !schema.False {
+		t.Errorf("ParseSchema(false) = %+v, %v", schema, err)
+	}
+}
+
+func TestSchemaMarshalJSON(t *testing.T) {
+	tests := []string{
+		`{"type":"integer","enum":[1,2]}`,
+		`{"type":["number","boolean"],"enum":[1.5,true]}`,
+		`{"type":["string","integer"],"enum":["1","a"]}`,
+		`{"enum":["1","true"]}`,
+		`{"type":"integer","enum":["a"]}`,
+	}
+	for _, in := range tests {
+		schema, err := ParseSchema([]byte(in))
+		if err != nil {
+			t.Errorf("%s: ParseSchema() error: %v", in, err)
+			continue
+		}
+		if got, err := json.Marshal(schema); err != nil {
+			t.Errorf("%s: json.Marshal() error: %v", in, err)
+		} else if string(got) != in {
+			t.Errorf("Wrong JSON\nWant %s\nGot  %s", in, got)
+		}
+	}
+}
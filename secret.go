@@ -0,0 +1,219 @@
+package jsontree
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Encrypted values are written as "enc:", the id of their key, ':' and the
+// base64 of the AES-GCM nonce and ciphertext. The key id is the additional
+// data of the ciphertext, so it can not be swapped for another.
+const encryptedPrefix = "enc:"
+
+// KeyProvider supplies the AES keys of encrypted values, which are 16, 24
+// or 32 bytes long. Key ids can not contain ':'.
+type KeyProvider interface {
+	// CurrentKey returns the key values are encrypted with, and its id
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key with id, to decrypt values
+	Key(id string) ([]byte, error)
+}
+
+// EncryptedValue is a Value that Value is serialized to encrypted, and
+// deserialized from once decrypted. Every call to Serialize encrypts with
+// the current key and a new nonce, so the result differs from call to call.
+// AllowPlaintext lets Deserialize pass values that are not encrypted as they
+// are, for trees where secrets are being encrypted.
+type EncryptedValue struct {
+	Value          Value
+	Keys           KeyProvider
+	AllowPlaintext bool
+}
+
+func (v *EncryptedValue) Serialize() ([]byte, error) {
+	plaintext, err := v.Value.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	return encryptValue(v.Keys, plaintext)
+}
+
+func (v *EncryptedValue) Deserialize(b []byte) error {
+	if !bytes.HasPrefix(b, []byte(encryptedPrefix)) {
+		if !v.AllowPlaintext {
+			return fmt.Errorf("value is not encrypted")
+		}
+		return v.Value.Deserialize(b)
+	}
+	_, plaintext, err := decryptValue(v.Keys, b)
+	if err != nil {
+		return err
+	}
+	return v.Value.Deserialize(plaintext)
+}
+
+// Reencrypt encrypts the encrypted leaves of node that are not encrypted with
+// the current key of keys with it, and returns how many there were. Other
+// leaves are left as they are. The leaves must hold the encrypted text, like
+// RawValues do: EncryptedValues are always serialized with the current key.
+func Reencrypt(node Node, keys KeyProvider) (int, error) {
+	if node == nil {
+		return 0, fmt.Errorf("node is nil")
+	}
+	current, _, err := keys.CurrentKey()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	var walk func(node Node) error
+	walk = func(node Node) error {
+		if nodes := node.Nodes(); len(nodes) > 0 {
+			for _, child := range nodes {
+				if child == nil {
+					return fmt.Errorf("invalid node: node.Nodes() contained nil")
+				}
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		value := node.Value()
+		if value == nil {
+			return fmt.Errorf("invalid node: len(node.Nodes()) == 0 and node.Value() == nil")
+		}
+		raw, err := value.Serialize()
+		if err != nil || !bytes.HasPrefix(raw, []byte(encryptedPrefix)) {
+			return err
+		}
+		id, plaintext, err := decryptValue(keys, raw)
+		if err != nil || id == current {
+			return err
+		}
+		if raw, err = encryptValue(keys, plaintext); err != nil {
+			return err
+		}
+		n++
+		return value.Deserialize(raw)
+	}
+	return n, walk(node)
+}
+
+func encryptValue(keys KeyProvider, plaintext []byte) ([]byte, error) {
+	id, key, err := keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	if id == "" || strings.IndexByte(id, ':') >= 0 {
+		return nil, fmt.Errorf("invalid key id \"%s\"", id)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, []byte(id))
+	dst := append([]byte(encryptedPrefix), id...)
+	dst = append(dst, ':')
+	return append(dst, base64.StdEncoding.EncodeToString(sealed)...), nil
+}
+
+func decryptValue(keys KeyProvider, raw []byte) (id string, plaintext []byte, err error) {
+	rest := raw[len(encryptedPrefix):]
+	i := bytes.IndexByte(rest, ':')
+	if i < 0 {
+		return "", nil, fmt.Errorf("encrypted value has no key id")
+	}
+	id = string(rest[:i])
+	sealed, err := base64.StdEncoding.Strict().DecodeString(string(rest[i+1:]))
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid encrypted value: %v", err)
+	}
+	key, err := keys.Key(id)
+	if err != nil {
+		return "", nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", nil, fmt.Errorf("invalid encrypted value: too short")
+	}
+	plaintext, err = gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(id))
+	if err != nil {
+		return "", nil, fmt.Errorf("decrypting with key \"%s\": %v", id, err)
+	}
+	return id, plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// KeyRing is a KeyProvider holding its keys in memory
+type KeyRing struct {
+	Current string
+	Keys    map[string][]byte
+}
+
+func (ring *KeyRing) CurrentKey() (string, []byte, error) {
+	key, err := ring.Key(ring.Current)
+	return ring.Current, key, err
+}
+
+func (ring *KeyRing) Key(id string) ([]byte, error) {
+	key, ok := ring.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key \"%s\"", id)
+	}
+	return key, nil
+}
+
+// LoadKeyFile reads a KeyRing from a file with a key per line: its id, a
+// space and the key in base64. The key of the last line is the current key.
+// Blank lines and lines starting with '#' are ignored.
+func LoadKeyFile(path string) (*KeyRing, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ring := &KeyRing{Keys: make(map[string][]byte)}
+	lines, _ := splitLines(data)
+	for i, line := range lines {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(string(line))
+		if len(fields) != 2 || strings.IndexByte(fields[0], ':') >= 0 {
+			return nil, fmt.Errorf("%s:%d: expected 'id base64-key'", path, i+1)
+		}
+		key, err := base64.StdEncoding.Strict().DecodeString(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid key: %v", path, i+1, err)
+		}
+		if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+			return nil, fmt.Errorf("%s:%d: keys must be 16, 24 or 32 bytes long, not %d", path, i+1, len(key))
+		}
+		ring.Keys[fields[0]] = key
+		ring.Current = fields[0]
+	}
+	if ring.Current == "" {
+		return nil, fmt.Errorf("%s: no keys", path)
+	}
+	return ring, nil
+}
@@ -0,0 +1,127 @@
+package jsontree
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKeyFile(t *testing.T, contents string) *KeyRing {
+	path := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ring, err := LoadKeyFile(path)
+	if err != nil {
+		t.Fatalf("LoadKeyFile() error: %v", err)
+	}
+	return ring
+}
+
+const (
+	testKey1 = "MDEyMzQ1Njc4OWFiY2RlZg=="                     // 16 bytes
+	testKey2 = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=" // 32 bytes
+)
+
+func TestEncryptedValue(t *testing.T) {
+	ring := testKeyFile(t, "# keys\nk1 "+testKey1+"\n")
+	secret := String("p@ss\"word")
+	v := &EncryptedValue{Value: &secret, Keys: ring}
+	raw, err := v.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize() error: %v", err)
+	}
+	if !bytes.HasPrefix(raw, []byte("enc:k1:")) || bytes.Contains(raw, []byte("word")) {
+		t.Errorf("Wrong encrypted value %s", raw)
+	}
+	if again, _ := v.Serialize(); bytes.Equal(again, raw) {
+		t.Errorf("Serialize() reused a nonce")
+	}
+	var got String
+	w := &EncryptedValue{Value: &got, Keys: ring}
+	if err := w.Deserialize(raw); err != nil || got != secret {
+		t.Errorf("Deserialize() = %v, value %s", err, got)
+	}
+
+	tests := []struct {
+		raw string
+		err string
+	}{
+		{"plain", "value is not encrypted"},
+		{"enc:k1", "encrypted value has no key id"},
+		{"enc:k1:!!", "invalid encrypted value: illegal base64 data at input byte 0"},
+		{"enc:k1:AAAA", "invalid encrypted value: too short"},
+		{"enc:k9:" + string(raw[len("enc:k1:"):]), `unknown key "k9"`},
+		{"enc:k1:" + strings.Repeat("A", 40), `decrypting with key "k1": cipher: message authentication failed`},
+	}
+	for _, test := range tests {
+		if err := w.Deserialize([]byte(test.raw)); err == nil || err.Error() != test.err {
+			t.Errorf("%s: Wrong error\nWant %s\nGot  %v", test.raw, test.err, err)
+		}
+	}
+	w.AllowPlaintext = true
+	if err := w.Deserialize([]byte("plain")); err != nil || got != "plain" {
+		t.Errorf("Deserialize() = %v, value %s", err, got)
+	}
+}
+
+func TestReencrypt(t *testing.T) {
+	old := testKeyFile(t, "k1 "+testKey1+"\n")
+	ring := testKeyFile(t, "k1 "+testKey1+"\nk2 "+testKey2+"\n")
+	if ring.Current != "k2" {
+		t.Fatalf("Wrong current key %s", ring.Current)
+	}
+	a, b := String("a"), String("b")
+	rawA, _ := (&EncryptedValue{Value: &a, Keys: old}).Serialize()
+	rawB, _ := (&EncryptedValue{Value: &b, Keys: ring}).Serialize()
+	node := &testNode{key: key("root"), nodes: []*testNode{
+		{key: key("a"), value: val(string(rawA))},
+		{key: key("sub"), nodes: []*testNode{{key: key("b"), value: val(string(rawB))}}},
+		{key: key("plain"), value: val("c")},
+	}}
+	n, err := Reencrypt(node, ring)
+	if err != nil || n != 1 {
+		t.Fatalf("Reencrypt() = %d, %v", n, err)
+	}
+	if got := node.nodes[0].value.b; !bytes.HasPrefix(got, []byte("enc:k2:")) {
+		t.Errorf("Leaf not re-encrypted: %s", got)
+	}
+	if got := node.nodes[1].nodes[0].value.b; !bytes.Equal(got, rawB) {
+		t.Errorf("Leaf encrypted with the current key changed: %s", got)
+	}
+	if got := node.nodes[2].value.b; string(got) != "c" {
+		t.Errorf("Plain leaf changed: %s", got)
+	}
+	var got String
+	if err := (&EncryptedValue{Value: &got, Keys: ring}).Deserialize(node.nodes[0].value.b); err != nil || got != "a" {
+		t.Errorf("Deserialize() = %v, value %s", err, got)
+	}
+	if _, err := Reencrypt(node, old); err == nil || err.Error() != `unknown key "k2"` {
+		t.Errorf("Wrong error %v", err)
+	}
+}
+
+func TestLoadKeyFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		contents string
+		err      string
+	}{
+		{"k1\n", ":1: expected 'id base64-key'"},
+		{"a:b " + testKey1, ":1: expected 'id base64-key'"},
+		{"\nk1 !", ":2: invalid key: illegal base64 data at input byte 0"},
+		{"k1 AAAA", ":1: keys must be 16, 24 or 32 bytes long, not 3"},
+		{"# none\n", ": no keys"},
+	}
+	for _, test := range tests {
+		path := filepath.Join(dir, "keys")
+		if err := os.WriteFile(path, []byte(test.contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadKeyFile(path); err == nil || err.Error() != path+test.err {
+			t.Errorf("%q: Wrong error\nWant %s\nGot  %v", test.contents, path+test.err, err)
+		}
+	}
+}
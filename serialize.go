@@ -12,55 +12,130 @@ type ByteWriter interface {
 }
 
 func SerializeNode(node Node, w io.Writer) error {
+	return EncodeOptions{}.SerializeNode(node, w)
+}
+
+func serializeDocument(node Node, w io.Writer, opts *EncodeOptions) error {
 	if node == nil {
 		return fmt.Errorf("node is nil")
 	}
-	var bw ByteWriter
-	if _bw, ok := w.(ByteWriter); ok {
-		bw = _bw
-	} else {
-		bw = bufio.NewWriter(w)
+	if opts.Schema != nil {
+		if err := opts.Schema.Validate(node); err != nil {
+			return err
+		}
+	}
+	if bw, ok := w.(ByteWriter); ok {
+		return serializeRoot(node, bw, opts)
 	}
-	if err := bw.WriteByte('{'); err != nil {
+	bw := bufio.NewWriter(w)
+	if err := serializeRoot(node, bw, opts); err != nil {
 		return err
 	}
-	if err := serializeNode(node, bw); err != nil {
+	return bw.Flush()
+}
+
+func serializeRoot(node Node, bw ByteWriter, opts *EncodeOptions) error {
+	if err := writeTrivia(node, TriviaDocStart, bw, opts); err != nil {
 		return err
 	}
-	return bw.WriteByte('}')
+	if opts.MultipleRoots {
+		if err := checkMultipleRoots(node); err != nil {
+			return err
+		}
+		if err := serializeNodes(node, node.Nodes(), bw, opts); err != nil {
+			return err
+		}
+	} else {
+		if err := bw.WriteByte('{'); err != nil {
+			return err
+		}
+		if err := serializeNode(node, bw, opts); err != nil {
+			return err
+		}
+		if err := bw.WriteByte('}'); err != nil {
+			return err
+		}
+	}
+	return writeTrivia(node, TriviaDocEnd, bw, opts)
 }
 
-func serializeNode(node Node, w ByteWriter) error {
+// checkMultipleRoots returns an error if node, written with MultipleRoots, has
+// a value rather than children, as the value would be lost
+func checkMultipleRoots(node Node) error {
+	if len(node.Nodes()) > 0 {
+		return nil
+	}
+	value := node.Value()
+	if value == nil {
+		return nil
+	}
+	b, err := value.Serialize()
+	if err != nil {
+		return err
+	}
+	if len(b) > 0 {
+		return fmt.Errorf("invalid node: MultipleRoots requires children, not a value")
+	}
+	return nil
+}
+
+func serializeNode(node Node, w ByteWriter, opts *EncodeOptions) error {
+	key := node.Key()
+	if opts.Canonical {
+		var err error
+		if key, err = canonicalString(key); err != nil {
+			return err
+		}
+	}
+	if err := writeTrivia(node, TriviaLeading, w, opts); err != nil {
+		return err
+	}
 	if err := w.WriteByte('"'); err != nil {
 		return err
 	}
-	if _, err := w.Write(node.Key()); err != nil {
+	if _, err := w.Write(key); err != nil {
 		return err
 	}
-	if _, err := w.Write(jsonBytes[1:]); err != nil { // write ":
+	if err := w.WriteByte('"'); err != nil {
+		return err
+	}
+	if err := writeTrivia(node, TriviaBeforeColon, w, opts); err != nil {
+		return err
+	}
+	if err := w.WriteByte(':'); err != nil {
+		return err
+	}
+	if err := writeTrivia(node, TriviaAfterColon, w, opts); err != nil {
 		return err
 	}
 	if nodes := node.Nodes(); len(nodes) > 0 {
-		if err := serializeNodes(nodes, w); err != nil {
+		if err := serializeNodes(node, nodes, w, opts); err != nil {
 			return err
 		}
 	} else if value := node.Value(); value != nil {
-		if err := serializeValue(value, w); err != nil {
+		if err := serializeValue(value, w, opts); err != nil {
 			return err
 		}
 	} else {
 		return fmt.Errorf("invalid node: len(node.Nodes()) == 0 and node.Value() == nil")
 	}
-	return nil
+	return writeTrivia(node, TriviaTrailing, w, opts)
 }
 
-func serializeValue(value Value, w ByteWriter) error {
-	if err := w.WriteByte('"'); err != nil {
+func serializeValue(value Value, w ByteWriter, opts *EncodeOptions) error {
+	b, err := value.Serialize()
+	if err != nil {
 		return err
 	}
-	if b, err := value.Serialize(); err != nil {
+	if opts.Canonical {
+		if b, err = canonicalString(b); err != nil {
+			return err
+		}
+	}
+	if err := w.WriteByte('"'); err != nil {
 		return err
-	} else if _, err = w.Write(b); err != nil {
+	}
+	if _, err := w.Write(b); err != nil {
 		return err
 	}
 	if err := w.WriteByte('"'); err != nil {
@@ -69,7 +144,12 @@ func serializeValue(value Value, w ByteWriter) error {
 	return nil
 }
 
-func serializeNodes(nodes []Node, w ByteWriter) error {
+// serializeNodes writes nodes, the children of parent
+func serializeNodes(parent Node, nodes []Node, w ByteWriter, opts *EncodeOptions) error {
+	nodes, err := opts.order(nodes)
+	if err != nil {
+		return err
+	}
 	if err := w.WriteByte('{'); err != nil {
 		return err
 	}
@@ -78,7 +158,7 @@ func serializeNodes(nodes []Node, w ByteWriter) error {
 		if node == nil {
 			return fmt.Errorf("invalid node: node.Nodes() contained nil")
 		}
-		if err := serializeNode(node, w); err != nil {
+		if err := serializeNode(node, w, opts); err != nil {
 			return err
 		}
 		if hasMoreChildren := i < n-1; hasMoreChildren {
@@ -87,6 +167,9 @@ func serializeNodes(nodes []Node, w ByteWriter) error {
 			}
 		}
 	}
+	if err := writeTrivia(parent, TriviaClosing, w, opts); err != nil {
+		return err
+	}
 	if err := w.WriteByte('}'); err != nil {
 		return err
 	}
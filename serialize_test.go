@@ -114,6 +114,31 @@ func TestSerializeNode(t *testing.T) {
 	}
 }
 
+func TestSerializeNodeFlush(t *testing.T) {
+	// Writers that are not ByteWriters are buffered internally, and must be flushed
+	mw := new(memWriter)
+	node := &testNode{key: key("a"), value: val("b")}
+	if err := SerializeNode(node, mw); err != nil {
+		t.Fatalf("SerializeNode() error: %v", err)
+	}
+	if got, want := string(mw.bs), `{"a":"b"}`; got != want {
+		t.Errorf("SerializeNode() wrote %s, want %s", got, want)
+	}
+}
+
+func TestSerializeNodeMultipleRootsValue(t *testing.T) {
+	// A value can not be written as the top level object
+	var buf bytes.Buffer
+	want := fmt.Errorf("invalid node: MultipleRoots requires children, not a value")
+	err := EncodeOptions{MultipleRoots: true}.SerializeNode(&testNode{key: key("doc"), value: val("hello")}, &buf)
+	if !errEqual(want, err) {
+		t.Errorf("Wrong error\nWant %v\nGot  %v", want, err)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("SerializeNode() wrote %s", buf.String())
+	}
+}
+
 // ========== Benchmarking ==========
 
 func getTestNode(width, depth int) *testNode {
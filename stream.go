@@ -0,0 +1,164 @@
+package jsontree
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// RecordError is returned by Decoder and Encoder when a record fails. Record
+// is the zero based index of the record, Line the line it starts on. For an
+// Encoder, Line is the line the record would have been written on, as failed
+// records are not written.
+type RecordError struct {
+	Record int
+	Line   int
+	Err    error
+}
+
+func (err *RecordError) Error() string {
+	return fmt.Sprintf("record %d (line %d): %v", err.Record, err.Line, err.Err)
+}
+
+func (err *RecordError) Unwrap() error {
+	return err.Err
+}
+
+// Decoder reads a stream of newline delimited documents (NDJSON).
+//
+// A record that fails to decode is reported as a *RecordError, after which
+// the Decoder skips to the next line, so the following records can still be
+// decoded.
+type Decoder struct {
+	r      *readerSource
+	opts   DecodeOptions
+	record int
+	resync bool
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return DecodeOptions{}.NewDecoder(r)
+}
+
+func (opts DecodeOptions) NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: newReaderSource(r), opts: opts}
+}
+
+// Decode decodes the next record into node. It returns io.EOF when there are
+// no more records.
+func (d *Decoder) Decode(node Node) error {
+	d.r.maxBytes = 0
+	if d.resync {
+		if err := d.skipLine(); err != nil {
+			return err
+		}
+		d.resync = false
+	}
+	// Skip blank lines between records
+	for {
+		bs, err := d.r.Peek(1)
+		if err != nil {
+			return err
+		}
+		if !isSpace(bs[0]) {
+			break
+		}
+		if _, err := d.r.ReadByte(); err != nil {
+			return err
+		}
+	}
+	start := d.r.position()
+	if d.opts.MaxBytes > 0 {
+		d.r.maxBytes = start.Offset + d.opts.MaxBytes
+	}
+	p := newParser(d.r, d.opts)
+	p.stream = true
+	err := deserializeNode(node, p)
+	d.r.maxBytes = 0
+	if err == nil {
+		err = d.readEndOfRecord()
+	}
+	if err != nil {
+		if d.r.position().Column != 1 {
+			// The rest of the line belongs to the failed record
+			d.resync = true
+		}
+		err = &RecordError{Record: d.record, Line: start.Line, Err: err}
+	}
+	d.record++
+	return err
+}
+
+// readEndOfRecord reads the whitespace and newline following a record
+func (d *Decoder) readEndOfRecord() error {
+	for {
+		b, err := d.r.ReadByte()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch b {
+		case '\n':
+			return nil
+		case ' ', '\t', '\r':
+		default:
+			return fmt.Errorf("expected newline after record. Got '%s'", string(b))
+		}
+	}
+}
+
+func (d *Decoder) skipLine() error {
+	for {
+		if b, err := d.r.ReadByte(); err != nil {
+			return err
+		} else if b == '\n' {
+			return nil
+		}
+	}
+}
+
+// Encoder writes nodes as a stream of newline delimited documents (NDJSON).
+//
+// Each record is serialized in full before it is written, so a node that
+// fails to serialize never leaves a partial line in the output. If the
+// underlying writer has a Flush method, it is called after every record.
+type Encoder struct {
+	w      io.Writer
+	opts   EncodeOptions
+	buf    bytes.Buffer
+	record int
+	lines  int // the number of lines written
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return EncodeOptions{}.NewEncoder(w)
+}
+
+func (opts EncodeOptions) NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, opts: opts}
+}
+
+func (e *Encoder) Encode(node Node) error {
+	record := e.record
+	e.record++
+	e.buf.Reset()
+	if err := e.opts.SerializeNode(node, &e.buf); err != nil {
+		return &RecordError{Record: record, Line: e.lines + 1, Err: err}
+	}
+	if bytes.IndexByte(e.buf.Bytes(), '\n') >= 0 {
+		err := fmt.Errorf("serialized node contains a newline")
+		return &RecordError{Record: record, Line: e.lines + 1, Err: err}
+	}
+	e.buf.WriteByte('\n')
+	if _, err := e.w.Write(e.buf.Bytes()); err != nil {
+		return &RecordError{Record: record, Line: e.lines + 1, Err: err}
+	}
+	e.lines++
+	if f, ok := e.w.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return &RecordError{Record: record, Line: e.lines, Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package jsontree
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecoder(t *testing.T) {
+	in := "{\"a\":\"v1\"}\n" +
+		"{\"b\":{\"c\":\"v2\"}}\r\n" +
+		"\n" +
+		"{\"d\":\"v3\"?}\n" + // syntax error
+		"{\"e\":\"v4\"}  \n" +
+		"{\"f\":\"v5\"} x\n" + // trailing garbage
+		"{\"g\":\"v6\"}"
+	want := []struct {
+		node *testNode
+		err  error
+	}{
+		{node: &testNode{key: key("a"), value: val("v1")}},
+		{node: &testNode{key: key("b"), nodes: []*testNode{{key: key("c"), value: val("v2")}}}},
+		{err: &RecordError{Record: 2, Line: 4, Err: &DeserializeError{Got: '?', Want: []byte{'}', ','}}}},
+		{node: &testNode{key: key("e"), value: val("v4")}},
+		{err: &RecordError{Record: 4, Line: 6, Err: fmt.Errorf("expected newline after record. Got 'x'")}},
+		{node: &testNode{key: key("g"), value: val("v6")}},
+	}
+	d := NewDecoder(strings.NewReader(in))
+	for i, w := range want {
+		node := new(testNode)
+		err := d.Decode(node)
+		if w.err != nil {
+			if !errEqual(w.err, err) {
+				t.Errorf("Decode() #%d: Wrong error\nWant %v\nGot  %v", i, w.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Decode() #%d: Unexpected error: %v", i, err)
+		} else if !nodeEqual(node, w.node) {
+			t.Errorf("Decode() #%d: Node was not as expected\nWant %v\nGot  %v", i, nodeString(w.node), nodeString(node))
+		}
+	}
+	if err := d.Decode(new(testNode)); err != io.EOF {
+		t.Errorf("Decode() at end of input returned %v, want io.EOF", err)
+	}
+}
+
+func TestDecoderOptions(t *testing.T) {
+	// Limits apply to each record
+	in := "{\"a\":\"v1\"}\n{\"b\":\"v2\"}\n{\"c\":\"v345\"}\n"
+	d := DecodeOptions{MaxBytes: 10}.NewDecoder(strings.NewReader(in))
+	for i := 0; i < 2; i++ {
+		if err := d.Decode(new(testNode)); err != nil {
+			t.Fatalf("Decode() #%d: Unexpected error: %v", i, err)
+		}
+	}
+	err := d.Decode(new(testNode))
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != "MaxBytes" {
+		t.Errorf("Decode() returned %v, want MaxBytes LimitError", err)
+	}
+	if err := d.Decode(new(testNode)); err != io.EOF {
+		t.Errorf("Decode() at end of input returned %v, want io.EOF", err)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	nodes := []*testNode{
+		{key: key("a"), value: val("v1")},
+		{key: key("b"), value: valErr("", fmt.Errorf("Test err"), nil)},
+		{key: key("c"), value: val("v\n2")},
+		{key: key("d"), nodes: []*testNode{{key: key("e"), value: val("v3")}}},
+		{key: key("f"), value: valErr("", fmt.Errorf("Test err"), nil)},
+	}
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	e := NewEncoder(bw)
+	var errs []error
+	for _, node := range nodes {
+		if err := e.Encode(node); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	want := "{\"a\":\"v1\"}\n{\"d\":{\"e\":\"v3\"}}\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Encoder wrote %q, want %q", got, want)
+	}
+	wantErrs := []error{
+		// Failed records are not written, so they do not count as lines
+		&RecordError{Record: 1, Line: 2, Err: fmt.Errorf("Test err")},
+		&RecordError{Record: 2, Line: 2, Err: fmt.Errorf("serialized node contains a newline")},
+		&RecordError{Record: 4, Line: 3, Err: fmt.Errorf("Test err")},
+	}
+	if len(errs) != len(wantErrs) {
+		t.Fatalf("Encode() returned %d errors, want %d", len(errs), len(wantErrs))
+	}
+	for i := range errs {
+		if !errEqual(wantErrs[i], errs[i]) {
+			t.Errorf("Encode() returned wrong error\nWant %v\nGot  %v", wantErrs[i], errs[i])
+		}
+	}
+
+	// Records written by the Encoder can be read by the Decoder
+	d := NewDecoder(&buf)
+	for _, want := range []*testNode{nodes[0], nodes[3]} {
+		node := new(testNode)
+		if err := d.Decode(node); err != nil {
+			t.Fatalf("Decode() error: %v", err)
+		} else if !nodeEqual(node, want) {
+			t.Errorf("Decode(): Node was not as expected\nWant %v\nGot  %v", nodeString(want), nodeString(node))
+		}
+	}
+}
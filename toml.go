@@ -0,0 +1,561 @@
+package jsontree
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+)
+
+// TOML tables are parent nodes, and key/value pairs leaves. Dotted keys and
+// inline tables are expanded into nested nodes. Values that are not strings,
+// like numbers, booleans and dates, are read as the text of the value, and
+// everything is written back as strings. Arrays are not supported.
+
+func DeserializeTOML(node Node, r io.Reader) error {
+	return DecodeOptions{}.DeserializeTOML(node, r)
+}
+
+func SerializeTOML(node Node, w io.Writer) error {
+	return EncodeOptions{}.SerializeTOML(node, w)
+}
+
+// DeserializeTOML reads a TOML document into node. Like DeserializeNode, the
+// document must have a single top-level key unless opts.MultipleRoots is
+// set. MaxBytes is the only limit that applies.
+func (opts DecodeOptions) DeserializeTOML(node Node, r io.Reader) error {
+	data, err := readAll(r, opts.MaxBytes)
+	if err != nil {
+		return err
+	}
+	t := &tomlReader{data: data, tree: treeBuilder{root: node, multi: opts.MultipleRoots}}
+	return t.read()
+}
+
+// SerializeTOML writes node as a TOML document. The leaves of every parent
+// are written as key/value pairs under the header of its table.
+func (opts EncodeOptions) SerializeTOML(node Node, w io.Writer) error {
+	if node == nil {
+		return fmt.Errorf("node is nil")
+	}
+	t := &tomlWriter{w: bufio.NewWriter(w), opts: &opts}
+	var err error
+	if opts.MultipleRoots {
+		err = t.writeTable(node.Nodes(), nil)
+	} else {
+		err = t.writeTable([]Node{node}, nil)
+	}
+	if err != nil {
+		return err
+	}
+	return t.w.Flush()
+}
+
+type tomlReader struct {
+	data     []byte
+	pos      int
+	tree     treeBuilder
+	table    [][]byte        // the path of the current table
+	tables   map[string]bool // the paths of the tables read, as joined by pathString
+	keyStart int             // the offset of the key being read
+}
+
+func (t *tomlReader) read() error {
+	for {
+		t.skipSpace(true)
+		if t.pos == len(t.data) {
+			return nil
+		}
+		if t.data[t.pos] == '[' {
+			if err := t.readTableHeader(); err != nil {
+				return err
+			}
+		} else {
+			t.keyStart = t.pos
+			path, err := t.readKey()
+			if err != nil {
+				return err
+			}
+			t.skipSpace(false)
+			if !t.consume('=') {
+				return t.errorf("expected '='")
+			}
+			t.skipSpace(false)
+			if err := t.readValue(append(t.table[:len(t.table):len(t.table)], path...)); err != nil {
+				return err
+			}
+		}
+		if err := t.readEndOfLine(); err != nil {
+			return err
+		}
+	}
+}
+
+func (t *tomlReader) readTableHeader() error {
+	start := t.pos
+	t.pos++ // '['
+	if t.consume('[') {
+		return t.errorf("arrays of tables are not supported")
+	}
+	t.skipSpace(false)
+	path, err := t.readKey()
+	if err != nil {
+		return err
+	}
+	t.skipSpace(false)
+	if !t.consume(']') {
+		return t.errorf("expected ']'")
+	}
+	if t.tables == nil {
+		t.tables = make(map[string]bool)
+	}
+	if t.tables[pathString(path)] {
+		t.pos = start
+		return t.errorf("table \"%s\" is defined twice", bytes.Join(path, []byte{'.'}))
+	}
+	t.tables[pathString(path)] = true
+	t.table = path
+	return nil
+}
+
+// readKey reads a dotted key, returning its escaped path
+func (t *tomlReader) readKey() ([][]byte, error) {
+	var path [][]byte
+	for {
+		var text []byte
+		var err error
+		switch {
+		case t.pos == len(t.data):
+			return nil, t.errorf("expected a key")
+		case t.data[t.pos] == '"' || t.data[t.pos] == '\'':
+			if text, err = t.readString(); err != nil {
+				return nil, err
+			}
+		default:
+			start := t.pos
+			for t.pos < len(t.data) && isTOMLBareKey(t.data[t.pos]) {
+				t.pos++
+			}
+			if t.pos == start {
+				return nil, t.errorf("expected a key")
+			}
+			text = t.data[start:t.pos]
+		}
+		path = append(path, appendEscaped(nil, text))
+		t.skipSpace(false)
+		if !t.consume('.') {
+			return path, nil
+		}
+		t.skipSpace(false)
+	}
+}
+
+// readValue reads the value of the key at path
+func (t *tomlReader) readValue(path [][]byte) error {
+	if t.pos == len(t.data) {
+		return t.errorf("expected a value")
+	}
+	var text []byte
+	var err error
+	switch t.data[t.pos] {
+	case '"', '\'':
+		if text, err = t.readString(); err != nil {
+			return err
+		}
+	case '{':
+		return t.readInlineTable(path)
+	case '[':
+		return t.errorf("arrays are not supported")
+	default:
+		if text, err = t.readScalar(); err != nil {
+			return err
+		}
+	}
+	// TOML does not allow a key to be defined twice
+	if t.tree.leaves[pathString(path)] {
+		t.pos = t.keyStart
+		return t.errorf("key \"%s\" is defined twice", bytes.Join(path, []byte{'.'}))
+	}
+	node, err := t.tree.leaf(path)
+	if err != nil {
+		t.pos = t.keyStart
+		return t.errorf("%v", err)
+	}
+	return node.Value().Deserialize(appendEscaped(nil, text))
+}
+
+func (t *tomlReader) readInlineTable(path [][]byte) error {
+	t.pos++ // '{'
+	t.skipSpace(false)
+	if t.consume('}') {
+		return nil // empty tables have no nodes
+	}
+	for {
+		key, err := t.readKey()
+		if err != nil {
+			return err
+		}
+		t.skipSpace(false)
+		if !t.consume('=') {
+			return t.errorf("expected '='")
+		}
+		t.skipSpace(false)
+		if err := t.readValue(append(path[:len(path):len(path)], key...)); err != nil {
+			return err
+		}
+		t.skipSpace(false)
+		if t.consume('}') {
+			return nil
+		}
+		if !t.consume(',') {
+			return t.errorf("expected ',' or '}'")
+		}
+		t.skipSpace(false)
+	}
+}
+
+// readString reads a basic or literal string, on one or several lines
+func (t *tomlReader) readString() ([]byte, error) {
+	quote := t.data[t.pos]
+	delim := []byte{quote}
+	multiline := bytes.HasPrefix(t.data[t.pos:], []byte{quote, quote, quote})
+	if multiline {
+		delim = t.data[t.pos : t.pos+3]
+		t.pos += 3
+		// A line break right after the delimiter is trimmed
+		if bytes.HasPrefix(t.data[t.pos:], []byte("\r\n")) {
+			t.pos += 2
+		} else {
+			t.consume('\n')
+		}
+	} else {
+		t.pos++
+	}
+	start := t.pos
+	for ; t.pos < len(t.data); t.pos++ {
+		b := t.data[t.pos]
+		if b == '\n' && !multiline {
+			return nil, t.errorf("unterminated string")
+		}
+		if b == '\\' && quote == '"' {
+			t.pos++
+			continue
+		}
+		if !bytes.HasPrefix(t.data[t.pos:], delim) {
+			continue
+		}
+		end := t.pos
+		t.pos += len(delim)
+		// Up to two quotes can precede the closing delimiter
+		for i := 0; multiline && i < 2 && t.consume(quote); i++ {
+			end++
+		}
+		raw := t.data[start:end]
+		if multiline {
+			raw = bytes.ReplaceAll(raw, []byte("\r\n"), []byte("\n"))
+		}
+		if quote == '\'' {
+			return raw, nil
+		}
+		if multiline {
+			raw = trimLineEndingBackslashes(raw)
+		}
+		text, err := unescapeTOML(raw)
+		if err != nil {
+			t.pos = start
+			return nil, t.errorf("%v", err)
+		}
+		return text, nil
+	}
+	return nil, t.errorf("unterminated string")
+}
+
+// unescapeTOML replaces the escape sequences of a basic string by what they
+// stand for
+func unescapeTOML(raw []byte) ([]byte, error) {
+	if bytes.IndexByte(raw, '\\') < 0 {
+		return raw, nil
+	}
+	text := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' {
+			text = append(text, raw[i])
+			continue
+		}
+		if i+1 == len(raw) {
+			return nil, fmt.Errorf("invalid escape at end of string")
+		}
+		i++
+		var r rune
+		switch esc := raw[i]; esc {
+		case 'b':
+			r = '\b'
+		case 't':
+			r = '\t'
+		case 'n':
+			r = '\n'
+		case 'f':
+			r = '\f'
+		case 'r':
+			r = '\r'
+		case '"', '\\':
+			r = rune(esc)
+		case 'u', 'U':
+			n := 4
+			if esc == 'U' {
+				n = 8
+			}
+			if i+n >= len(raw) {
+				return nil, fmt.Errorf("invalid escape '\\%c'", esc)
+			}
+			v, err := strconv.ParseUint(string(raw[i+1:i+1+n]), 16, 32)
+			if err != nil || !utf8.ValidRune(rune(v)) {
+				return nil, fmt.Errorf("invalid escape '\\%s'", raw[i:i+1+n])
+			}
+			r = rune(v)
+			i += n
+		default:
+			return nil, fmt.Errorf("invalid escape '\\%c'", esc)
+		}
+		text = utf8.AppendRune(text, r)
+	}
+	return text, nil
+}
+
+// trimLineEndingBackslashes removes the backslashes at the end of lines of a
+// multi-line basic string, and the whitespace following them
+func trimLineEndingBackslashes(raw []byte) []byte {
+	if bytes.IndexByte(raw, '\\') < 0 {
+		return raw
+	}
+	trimmed := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' {
+			trimmed = append(trimmed, raw[i])
+			continue
+		}
+		j := i + 1
+		for j < len(raw) && (raw[j] == ' ' || raw[j] == '\t') {
+			j++
+		}
+		if j < len(raw) && raw[j] == '\n' {
+			for j < len(raw) && (raw[j] == ' ' || raw[j] == '\t' || raw[j] == '\n') {
+				j++
+			}
+			i = j - 1
+			continue
+		}
+		if i+1 < len(raw) {
+			trimmed = append(trimmed, raw[i], raw[i+1])
+			i++
+		}
+	}
+	return trimmed
+}
+
+// readScalar reads a number, a boolean or a date, returning its text
+func (t *tomlReader) readScalar() ([]byte, error) {
+	start := t.pos
+	for t.pos < len(t.data) && isTOMLScalar(t.data[t.pos]) {
+		t.pos++
+		// A space can separate the date and the time
+		if t.pos-start == 10 && t.pos+1 < len(t.data) && t.data[t.pos] == ' ' && isDigit(t.data[t.pos+1]) && t.data[start+4] == '-' {
+			t.pos++
+		}
+	}
+	text := t.data[start:t.pos]
+	if len(text) == 0 {
+		return nil, t.errorf("expected a value")
+	}
+	if !tomlScalar.Match(text) {
+		t.pos = start
+		return nil, t.errorf("invalid value '%s'", text)
+	}
+	return text, nil
+}
+
+func (t *tomlReader) readEndOfLine() error {
+	t.skipSpace(false)
+	if t.pos < len(t.data) && t.data[t.pos] == '#' {
+		for t.pos < len(t.data) && t.data[t.pos] != '\n' {
+			t.pos++
+		}
+	}
+	if t.pos == len(t.data) || t.consume('\n') || bytes.HasPrefix(t.data[t.pos:], []byte("\r\n")) {
+		return nil
+	}
+	return t.errorf("expected the end of the line")
+}
+
+// skipSpace skips spaces and tabs, and line breaks and comments if lines is set
+func (t *tomlReader) skipSpace(lines bool) {
+	for t.pos < len(t.data) {
+		switch t.data[t.pos] {
+		case ' ', '\t':
+		case '\r', '\n':
+			if !lines {
+				return
+			}
+		case '#':
+			if !lines {
+				return
+			}
+			for t.pos < len(t.data) && t.data[t.pos] != '\n' {
+				t.pos++
+			}
+			continue
+		default:
+			return
+		}
+		t.pos++
+	}
+}
+
+func (t *tomlReader) consume(b byte) bool {
+	if t.pos < len(t.data) && t.data[t.pos] == b {
+		t.pos++
+		return true
+	}
+	return false
+}
+
+func (t *tomlReader) errorf(format string, args ...interface{}) error {
+	pos := Position{Line: 1, Column: 1}
+	for _, b := range t.data[:t.pos] {
+		pos.advance(b)
+	}
+	return &SyntaxError{Path: t.table, Pos: pos, Err: fmt.Errorf(format, args...)}
+}
+
+// tomlScalar matches the integers, floats, booleans and dates of TOML
+var tomlScalar = regexp.MustCompile(`^(?:` +
+	`true|false|` +
+	`[+-]?(?:0|[1-9](?:_?[0-9])*)|0x[0-9A-Fa-f](?:_?[0-9A-Fa-f])*|0o[0-7](?:_?[0-7])*|0b[01](?:_?[01])*|` +
+	`[+-]?(?:0|[1-9](?:_?[0-9])*)(?:\.[0-9](?:_?[0-9])*(?:[eE][+-]?[0-9](?:_?[0-9])*)?|[eE][+-]?[0-9](?:_?[0-9])*)|` +
+	`[+-]?(?:inf|nan)|` +
+	`[0-9]{4}-[0-9]{2}-[0-9]{2}(?:[Tt ][0-9]{2}:[0-9]{2}:[0-9]{2}(?:\.[0-9]+)?(?:[Zz]|[+-][0-9]{2}:[0-9]{2})?)?|` +
+	`[0-9]{2}:[0-9]{2}:[0-9]{2}(?:\.[0-9]+)?` +
+	`)$`)
+
+func isTOMLBareKey(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || isDigit(b) || b == '_' || b == '-'
+}
+
+func isTOMLScalar(b byte) bool {
+	return isTOMLBareKey(b) || b == '+' || b == '.' || b == ':'
+}
+
+type tomlWriter struct {
+	w       *bufio.Writer
+	opts    *EncodeOptions
+	written bool
+}
+
+// writeTable writes nodes, the children of the table at path. Leaves come
+// first, as they would otherwise belong to the tables written before them.
+func (t *tomlWriter) writeTable(nodes []Node, path [][]byte) error {
+	nodes, err := t.opts.order(nodes)
+	if err != nil {
+		return err
+	}
+	var tables []Node
+	header := len(path) > 0
+	for _, node := range nodes {
+		if node == nil {
+			return fmt.Errorf("invalid node: node.Nodes() contained nil")
+		}
+		if len(node.Nodes()) > 0 {
+			tables = append(tables, node)
+			continue
+		}
+		if header {
+			if err := t.writeHeader(path); err != nil {
+				return err
+			}
+			header = false
+		}
+		if err := t.writeLeaf(node); err != nil {
+			return err
+		}
+	}
+	for _, node := range tables {
+		if err := t.writeTable(node.Nodes(), append(path[:len(path):len(path)], node.Key())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *tomlWriter) writeHeader(path [][]byte) error {
+	if t.written {
+		t.w.WriteByte('\n')
+	}
+	t.w.WriteByte('[')
+	for i, key := range path {
+		if i > 0 {
+			t.w.WriteByte('.')
+		}
+		if err := t.writeKey(key); err != nil {
+			return err
+		}
+	}
+	t.w.WriteString("]\n")
+	return nil
+}
+
+func (t *tomlWriter) writeLeaf(node Node) error {
+	value := node.Value()
+	if value == nil {
+		return fmt.Errorf("invalid node: len(node.Nodes()) == 0 and node.Value() == nil")
+	}
+	raw, err := value.Serialize()
+	if err != nil {
+		return err
+	}
+	text, err := unescapeString(raw)
+	if err != nil {
+		return err
+	}
+	if err := t.writeKey(node.Key()); err != nil {
+		return err
+	}
+	t.w.WriteString(" = ")
+	t.w.Write(appendTOMLString(nil, text))
+	t.w.WriteByte('\n')
+	t.written = true
+	return nil
+}
+
+// writeKey writes the escaped key, bare if it can be
+func (t *tomlWriter) writeKey(key []byte) error {
+	text, err := unescapeString(key)
+	if err != nil {
+		return err
+	}
+	bare := len(text) > 0
+	for _, b := range text {
+		bare = bare && isTOMLBareKey(b)
+	}
+	if bare {
+		t.w.Write(text)
+	} else {
+		t.w.Write(appendTOMLString(nil, text))
+	}
+	return nil
+}
+
+// appendTOMLString appends text to dst as a basic string
+func appendTOMLString(dst, text []byte) []byte {
+	dst = append(dst, '"')
+	for _, b := range appendEscaped(nil, text) {
+		if b == 0x7f {
+			dst = append(dst, `\u007f`...)
+		} else {
+			dst = append(dst, b)
+		}
+	}
+	return append(dst, '"')
+}
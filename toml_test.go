@@ -0,0 +1,211 @@
+package jsontree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDeserializeTOML(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		multi bool
+		want  string
+		err   string
+	}{
+		{
+			name: "tables",
+			in:   "# translations\n[en]\ngreeting = \"Hello\" # comment\n\n[en.menu]\nopen = 'Open file'\n",
+			want: `{"en":{"greeting":"Hello","menu":{"open":"Open file"}}}`,
+		},
+		{
+			name: "dotted keys",
+			in:   "en.menu . open = \"Open\"\nen.\"a.b\" = \"x\"\n[en]\nmenu.close = \"Close\"\n",
+			want: `{"en":{"menu":{"open":"Open","close":"Close"},"a.b":"x"}}`,
+		},
+		{
+			name: "inline tables",
+			in:   "en = { menu = { open = \"Open\" }, bye.short = \"Bye\" }\n",
+			want: `{"en":{"menu":{"open":"Open"},"bye":{"short":"Bye"}}}`,
+		},
+		{
+			name: "escapes",
+			in:   "[en]\na = \"say \\\"hi\\\"\\n\\t\\u00e9\"\nb = 'C:\\path'\n",
+			want: `{"en":{"a":"say \"hi\"\n\té","b":"C:\\path"}}`,
+		},
+		{
+			name: "multi-line strings",
+			in:   "[en]\na = \"\"\"\nline 1\nline 2\"\"\"\nb = \"\"\"one \\\n    two\"\"\"\nc = '''\n'x' \\n'''\nd = \"\"\"\"q\"\"\"\"\n",
+			want: `{"en":{"a":"line 1\nline 2","b":"one two","c":"'x' \\n","d":"\"q\""}}`,
+		},
+		{
+			name: "other values",
+			in:   "[en]\nn = 42\nf = -1.5e3\nb = true\nd = 1979-05-27 07:32:00Z\nh = 0xdead_beef\ni = +inf\nt = 07:32:00.5\n",
+			want: `{"en":{"n":"42","f":"-1.5e3","b":"true","d":"1979-05-27 07:32:00Z","h":"0xdead_beef","i":"+inf","t":"07:32:00.5"}}`,
+		},
+		{
+			name: "CRLF line breaks",
+			in:   "[en]\r\na = \"x\"\r\n",
+			want: `{"en":{"a":"x"}}`,
+		},
+		{
+			name:  "multiple roots",
+			in:    "a = \"x\"\n[b]\nc = \"y\"\n",
+			multi: true,
+			want:  `{"a":"x","b":{"c":"y"}}`,
+		},
+		{
+			name: "several roots",
+			in:   "a = \"x\"\nb = \"y\"\n",
+			err:  `2:1: expected 1 root node (at "")`,
+		},
+		{
+			name: "array",
+			in:   "[en]\na = [\"x\"]\n",
+			err:  `2:5: arrays are not supported (at "en")`,
+		},
+		{
+			name: "array of tables",
+			in:   "[[en]]\n",
+			err:  `1:3: arrays of tables are not supported (at "")`,
+		},
+		{
+			name: "missing equals",
+			in:   "[en]\na \"x\"\n",
+			err:  `2:3: expected '=' (at "en")`,
+		},
+		{
+			name: "unterminated string",
+			in:   "[en]\na = \"x\nb = \"y\"\n",
+			err:  `2:7: unterminated string (at "en")`,
+		},
+		{
+			name: "invalid value",
+			in:   "[en]\na = x\n",
+			err:  `2:5: invalid value 'x' (at "en")`,
+		},
+		{
+			name: "invalid number",
+			in:   "[en]\na = 12abc\n",
+			err:  `2:5: invalid value '12abc' (at "en")`,
+		},
+		{
+			name: "leading zero",
+			in:   "[en]\na = 012\n",
+			err:  `2:5: invalid value '012' (at "en")`,
+		},
+		{
+			name: "YAML escape",
+			in:   "[en]\na = \"\\x41\"\n",
+			err:  `2:6: invalid escape '\x' (at "en")`,
+		},
+		{
+			name: "key defined twice",
+			in:   "[en]\na = \"1\"\na = \"2\"\n",
+			err:  `3:1: key "en.a" is defined twice (at "en")`,
+		},
+		{
+			name: "table defined twice",
+			in:   "[en.a]\nx = \"1\"\n[en.b]\ny = \"2\"\n[ en.a ]\nz = \"3\"\n",
+			err:  `5:1: table "en.a" is defined twice (at "en.b")`,
+		},
+		{
+			name: "value used as a table",
+			in:   "[en]\na = \"1\"\na.b = \"2\"\n",
+			err:  `3:1: key "en.a" is both a value and a parent (at "en")`,
+		},
+		{
+			name: "table used as a value",
+			in:   "[en]\na.b = \"1\"\na = \"2\"\n",
+			err:  `3:1: key "en.a" is both a value and a parent (at "en")`,
+		},
+		{
+			name: "two values on a line",
+			in:   "[en]\na = \"x\" b = \"y\"\n",
+			err:  `2:9: expected the end of the line (at "en")`,
+		},
+	}
+	for _, test := range tests {
+		node := &testNode{}
+		err := DecodeOptions{MultipleRoots: test.multi}.DeserializeTOML(node, strings.NewReader(test.in))
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: Wrong error\nWant %s\nGot  %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %v", test.name, err)
+			continue
+		}
+		var buf bytes.Buffer
+		if err := (EncodeOptions{MultipleRoots: test.multi}).SerializeNode(node, &buf); err != nil {
+			t.Errorf("%s: SerializeNode() error: %v", test.name, err)
+		} else if got := buf.String(); got != test.want {
+			t.Errorf("%s: Wrong tree\nWant %s\nGot  %s", test.name, test.want, got)
+		}
+	}
+}
+
+func TestSerializeTOML(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  string
+		multi bool
+		want  string
+	}{
+		{
+			name: "tables",
+			json: `{"en":{"menu":{"open":"Open"},"greeting":"Hello","a b":{"c.d":"\"x\"\n\u007f"}}}`,
+			want: "[en]\ngreeting = \"Hello\"\n\n[en.menu]\nopen = \"Open\"\n\n[en.\"a b\"]\n\"c.d\" = \"\\\"x\\\"\\n\\u007f\"\n",
+		},
+		{
+			name: "implicit tables",
+			json: `{"en":{"a":{"b":{"c":"x"}}}}`,
+			want: "[en.a.b]\nc = \"x\"\n",
+		},
+		{
+			name: "leaf root",
+			json: `{"en":"x"}`,
+			want: "en = \"x\"\n",
+		},
+		{
+			name:  "multiple roots",
+			json:  `{"b":{"c":"y"},"a":"x"}`,
+			multi: true,
+			want:  "a = \"x\"\n\n[b]\nc = \"y\"\n",
+		},
+	}
+	for _, test := range tests {
+		node := &testNode{}
+		if err := (DecodeOptions{MultipleRoots: test.multi}).DeserializeNode(node, strings.NewReader(test.json)); err != nil {
+			t.Fatalf("%s: DeserializeNode() error: %v", test.name, err)
+		}
+		var buf bytes.Buffer
+		if err := (EncodeOptions{MultipleRoots: test.multi}).SerializeTOML(node, &buf); err != nil {
+			t.Errorf("%s: SerializeTOML() error: %v", test.name, err)
+			continue
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("%s: Wrong TOML\nWant %q\nGot  %q", test.name, test.want, got)
+		}
+		// Reading the TOML back gives the same tree, apart from the order of
+		// leaves and tables
+		back := &testNode{}
+		if err := (DecodeOptions{MultipleRoots: test.multi}).DeserializeTOML(back, &buf); err != nil {
+			t.Errorf("%s: DeserializeTOML() error: %v", test.name, err)
+			continue
+		}
+		want, got := new(bytes.Buffer), new(bytes.Buffer)
+		opts := EncodeOptions{MultipleRoots: test.multi, Canonical: true}
+		if err := opts.SerializeNode(node, want); err != nil {
+			t.Fatalf("%s: SerializeNode() error: %v", test.name, err)
+		}
+		if err := opts.SerializeNode(back, got); err != nil {
+			t.Errorf("%s: SerializeNode() error: %v", test.name, err)
+		} else if got.String() != want.String() {
+			t.Errorf("%s: Round trip changed the tree\nWant %s\nGot  %s", test.name, want, got)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package jsontree
+
+// TriviaKind is where in a document a piece of trivia is found. Trivia is what
+// a document holds besides keys and values: the whitespace between them, and
+// the comments of the JSONC and JSON5 dialects.
+type TriviaKind int
+
+const (
+	TriviaDocStart    TriviaKind = iota // before the document. Only on the root node.
+	TriviaLeading                       // before the key
+	TriviaBeforeColon                   // between the key and the colon
+	TriviaAfterColon                    // between the colon and the value
+	TriviaTrailing                      // after the value, before the following ','
+	TriviaClosing                       // after the last child, before the closing bracket
+	TriviaDocEnd                        // after the document. Only on the root node.
+	numTriviaKinds
+)
+
+// TriviaNode is a Node that keeps the trivia surrounding it in a document.
+//
+// When the node passed to DeserializeNode is a TriviaNode, the trivia of every
+// node is passed to SetTrivia, for the nodes that are TriviaNodes too.
+// SerializeNode writes the trivia of TriviaNodes back, unless
+// EncodeOptions.Canonical is set. Together with nodes keeping the order they
+// were added in, that makes a document round trip byte for byte.
+type TriviaNode interface {
+	Node
+	Trivia(kind TriviaKind) []byte
+	SetTrivia(kind TriviaKind, trivia []byte)
+}
+
+// DocumentNode is a TriviaNode and a CommentNode that keeps its children in
+// the order they were added, and its value as raw bytes. The zero value is an
+// empty node.
+type DocumentNode struct {
+	key     []byte
+	value   RawValue
+	nodes   []Node
+	trivia  [numTriviaKinds][]byte
+	comment []byte
+}
+
+func (n *DocumentNode) Key() []byte {
+	return n.key
+}
+
+func (n *DocumentNode) SetKey(key []byte) {
+	n.key = key
+}
+
+func (n *DocumentNode) Value() Value {
+	return &n.value
+}
+
+func (n *DocumentNode) Nodes() []Node {
+	return n.nodes
+}
+
+func (n *DocumentNode) AddNode(key []byte) Node {
+	node := &DocumentNode{key: key}
+	n.nodes = append(n.nodes, node)
+	return node
+}
+
+func (n *DocumentNode) Trivia(kind TriviaKind) []byte {
+	if kind < 0 || kind >= numTriviaKinds {
+		return nil
+	}
+	return n.trivia[kind]
+}
+
+func (n *DocumentNode) SetTrivia(kind TriviaKind, trivia []byte) {
+	if kind >= 0 && kind < numTriviaKinds {
+		n.trivia[kind] = trivia
+	}
+}
+
+func (n *DocumentNode) Comment() []byte {
+	return n.comment
+}
+
+func (n *DocumentNode) SetComment(comment []byte) {
+	n.comment = comment
+}
+
+// RawValue is a Value holding the contents of a JSON string as is, escape
+// sequences included.
+type RawValue []byte
+
+func (v *RawValue) Serialize() ([]byte, error) {
+	return *v, nil
+}
+
+func (v *RawValue) Deserialize(b []byte) error {
+	*v = b
+	return nil
+}
+
+// triviaSetter returns the function the parser passes trivia to, for
+// deserializing into root
+func triviaSetter(root TriviaNode, multipleRoots bool) func(path [][]byte, kind TriviaKind, trivia []byte) {
+	return func(path [][]byte, kind TriviaKind, trivia []byte) {
+		var node Node
+		switch {
+		case len(path) == 0:
+			node = root
+		case multipleRoots:
+			node = getOrAddNode(root, path...)
+		case len(path) == 1:
+			node = root
+		default:
+			node = getOrAddNode(root, path[1:]...)
+		}
+		if tn, ok := node.(TriviaNode); ok {
+			tn.SetTrivia(kind, trivia)
+		}
+	}
+}
+
+// writeTrivia writes the trivia of node, if it has any
+func writeTrivia(node Node, kind TriviaKind, w ByteWriter, opts *EncodeOptions) error {
+	if opts.Canonical {
+		return nil
+	}
+	if tn, ok := node.(TriviaNode); ok {
+		trivia := tn.Trivia(kind)
+		if opts.OmitComments {
+			trivia = stripComments(trivia)
+		}
+		if len(trivia) > 0 {
+			_, err := w.Write(trivia)
+			return err
+		}
+	}
+	return nil
+}
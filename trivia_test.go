@@ -0,0 +1,98 @@
+package jsontree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDocumentNodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		multi bool
+	}{
+		{
+			name: "compact",
+			in:   `{"root":{"b":"1","a":{"y":"2","x":"3"}}}`,
+		},
+		{
+			name: "pretty printed",
+			in:   "\n{\n  \"root\": {\n    \"b\": \"1\",\n    \"a\" : {\"y\":\"2\" , \"x\":\t\"3\"}\n  }\n}\n\n",
+		},
+		{
+			name:  "multiple roots",
+			in:    "{\n  \"b\": \"1\",\n\n  \"a\": {\n    \"x\": \"\\\"3\\\"\"\n  }\n}\n",
+			multi: true,
+		},
+		{
+			name:  "empty",
+			in:    " { \n } ",
+			multi: true,
+		},
+	}
+	for _, test := range tests {
+		for _, fromBytes := range []bool{false, true} {
+			node := new(DocumentNode)
+			var err error
+			opts := DecodeOptions{MultipleRoots: test.multi}
+			if fromBytes {
+				err = opts.DeserializeBytes(node, []byte(test.in))
+			} else {
+				err = opts.DeserializeNode(node, strings.NewReader(test.in))
+			}
+			if err != nil {
+				t.Errorf("%s: Unexpected error: %v", test.name, err)
+				continue
+			}
+			var buf bytes.Buffer
+			if err := (EncodeOptions{MultipleRoots: test.multi}).SerializeNode(node, &buf); err != nil {
+				t.Errorf("%s: SerializeNode() error: %v", test.name, err)
+			} else if got := buf.String(); got != test.in {
+				t.Errorf("%s: Round trip changed the document\nWant %q\nGot  %q", test.name, test.in, got)
+			}
+		}
+	}
+}
+
+func TestDocumentNodeEdit(t *testing.T) {
+	in := "{\n  \"greeting\": \"Hello\",\n  \"farewell\": \"Bye\"\n}\n"
+	node := new(DocumentNode)
+	if err := (DecodeOptions{MultipleRoots: true}).DeserializeNode(node, strings.NewReader(in)); err != nil {
+		t.Fatalf("DeserializeNode() error: %v", err)
+	}
+	if err := getNode(node, key("farewell")).Value().Deserialize(key("Goodbye")); err != nil {
+		t.Fatalf("Deserialize() error: %v", err)
+	}
+	getOrAddNode(node, key("new")).Value().Deserialize(key("!"))
+	var buf bytes.Buffer
+	if err := (EncodeOptions{MultipleRoots: true}).SerializeNode(node, &buf); err != nil {
+		t.Fatalf("SerializeNode() error: %v", err)
+	}
+	want := "{\n  \"greeting\": \"Hello\",\n  \"farewell\": \"Goodbye\",\"new\":\"!\"\n}\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Edited document\nWant %q\nGot  %q", want, got)
+	}
+
+	// Canonical serialization leaves out the trivia
+	got, err := Canonicalize(getNode(node, key("greeting")))
+	if err != nil {
+		t.Fatalf("Canonicalize() error: %v", err)
+	}
+	if want := `{"greeting":"Hello"}`; string(got) != want {
+		t.Errorf("Canonicalize() = %s, want %s", got, want)
+	}
+}
+
+func TestDocumentNodeTrivia(t *testing.T) {
+	n := new(DocumentNode)
+	n.SetTrivia(TriviaLeading, key(" "))
+	n.SetTrivia(numTriviaKinds, key("x"))
+	n.SetTrivia(-1, key("x"))
+	if got := string(n.Trivia(TriviaLeading)); got != " " {
+		t.Errorf("Trivia(TriviaLeading) = %q, want \" \"", got)
+	}
+	if n.Trivia(numTriviaKinds) != nil || n.Trivia(-1) != nil {
+		t.Errorf("Trivia() of invalid kind is not nil")
+	}
+}
@@ -0,0 +1,294 @@
+package jsontree
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Values of common Go types. Serialize escapes the text of the value, and
+// Deserialize parses the text of the JSON string strictly: numbers must be
+// JSON numbers, booleans true or false. The value is left unchanged when
+// Deserialize fails.
+
+type String string
+
+func (v *String) Serialize() ([]byte, error) {
+	return appendEscaped(nil, []byte(*v)), nil
+}
+
+func (v *String) Deserialize(b []byte) error {
+	text, err := unescapeString(b)
+	if err != nil {
+		return err
+	}
+	*v = String(text)
+	return nil
+}
+
+type Int int
+
+func (v *Int) Serialize() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(*v), 10), nil
+}
+
+func (v *Int) Deserialize(b []byte) error {
+	n, err := parseInt(b, strconv.IntSize)
+	if err != nil {
+		return err
+	}
+	*v = Int(n)
+	return nil
+}
+
+type Int64 int64
+
+func (v *Int64) Serialize() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(*v), 10), nil
+}
+
+func (v *Int64) Deserialize(b []byte) error {
+	n, err := parseInt(b, 64)
+	if err != nil {
+		return err
+	}
+	*v = Int64(n)
+	return nil
+}
+
+type Uint uint
+
+func (v *Uint) Serialize() ([]byte, error) {
+	return strconv.AppendUint(nil, uint64(*v), 10), nil
+}
+
+func (v *Uint) Deserialize(b []byte) error {
+	text, err := unescapeString(b)
+	if err != nil {
+		return err
+	}
+	if !jsonInteger.Match(text) || text[0] == '-' {
+		return valueError("unsigned integer", text, strconv.ErrSyntax)
+	}
+	n, err := strconv.ParseUint(string(text), 10, strconv.IntSize)
+	if err != nil {
+		return valueError("unsigned integer", text, err)
+	}
+	*v = Uint(n)
+	return nil
+}
+
+type Float64 float64
+
+func (v *Float64) Serialize() ([]byte, error) {
+	if math.IsInf(float64(*v), 0) || math.IsNaN(float64(*v)) {
+		return nil, fmt.Errorf("%v is not a JSON number", float64(*v))
+	}
+	return strconv.AppendFloat(nil, float64(*v), 'g', -1, 64), nil
+}
+
+func (v *Float64) Deserialize(b []byte) error {
+	text, err := unescapeString(b)
+	if err != nil {
+		return err
+	}
+	if !jsonNumber.Match(text) {
+		return valueError("number", text, strconv.ErrSyntax)
+	}
+	f, err := strconv.ParseFloat(string(text), 64)
+	if err != nil {
+		return valueError("number", text, err)
+	}
+	*v = Float64(f)
+	return nil
+}
+
+type Bool bool
+
+func (v *Bool) Serialize() ([]byte, error) {
+	return strconv.AppendBool(nil, bool(*v)), nil
+}
+
+func (v *Bool) Deserialize(b []byte) error {
+	text, err := unescapeString(b)
+	if err != nil {
+		return err
+	}
+	switch string(text) {
+	case "true":
+		*v = true
+	case "false":
+		*v = false
+	default:
+		return valueError("boolean", text, fmt.Errorf("expected true or false"))
+	}
+	return nil
+}
+
+// Duration is written like "1h30m", as by time.Duration.String
+type Duration time.Duration
+
+func (v *Duration) Serialize() ([]byte, error) {
+	return []byte(time.Duration(*v).String()), nil
+}
+
+func (v *Duration) Deserialize(b []byte) error {
+	text, err := unescapeString(b)
+	if err != nil {
+		return err
+	}
+	d, err := time.ParseDuration(string(text))
+	if err != nil {
+		return valueError("duration", text, fmt.Errorf("expected a number and a unit, like 1h30m"))
+	}
+	*v = Duration(d)
+	return nil
+}
+
+// Time is written in the RFC 3339 format, with fractional seconds if any
+type Time time.Time
+
+func (v *Time) Serialize() ([]byte, error) {
+	return []byte(time.Time(*v).Format(time.RFC3339Nano)), nil
+}
+
+func (v *Time) Deserialize(b []byte) error {
+	text, err := unescapeString(b)
+	if err != nil {
+		return err
+	}
+	t, err := time.Parse(time.RFC3339Nano, string(text))
+	if err != nil {
+		return valueError("RFC 3339 time", text, err)
+	}
+	*v = Time(t)
+	return nil
+}
+
+// Bytes is written in standard base64, with padding
+type Bytes []byte
+
+func (v *Bytes) Serialize() ([]byte, error) {
+	return []byte(base64.StdEncoding.EncodeToString(*v)), nil
+}
+
+func (v *Bytes) Deserialize(b []byte) error {
+	text, err := unescapeString(b)
+	if err != nil {
+		return err
+	}
+	data, err := base64.StdEncoding.Strict().DecodeString(string(text))
+	if err != nil {
+		return valueError("base64", text, err)
+	}
+	*v = data
+	return nil
+}
+
+// URL must be absolute. The empty string is the zero URL.
+type URL url.URL
+
+func (v *URL) Serialize() ([]byte, error) {
+	return appendEscaped(nil, []byte((*url.URL)(v).String())), nil
+}
+
+func (v *URL) Deserialize(b []byte) error {
+	text, err := unescapeString(b)
+	if err != nil {
+		return err
+	}
+	if len(text) == 0 {
+		*v = URL{}
+		return nil
+	}
+	u, err := url.Parse(string(text))
+	if err != nil {
+		return valueError("URL", text, err.(*url.Error).Err)
+	}
+	if !u.IsAbs() || u.Opaque == "" && u.Host == "" && u.Path == "" {
+		return valueError("URL", text, fmt.Errorf("expected an absolute URL"))
+	}
+	*v = URL(*u)
+	return nil
+}
+
+// IP is an IPv4 or IPv6 address. The empty string is the zero IP.
+type IP net.IP
+
+func (v *IP) Serialize() ([]byte, error) {
+	if len(*v) == 0 {
+		return []byte{}, nil
+	}
+	return []byte(net.IP(*v).String()), nil
+}
+
+func (v *IP) Deserialize(b []byte) error {
+	text, err := unescapeString(b)
+	if err != nil {
+		return err
+	}
+	if len(text) == 0 {
+		*v = nil
+		return nil
+	}
+	ip := net.ParseIP(string(text))
+	if ip == nil {
+		return valueError("IP address", text, strconv.ErrSyntax)
+	}
+	*v = IP(ip)
+	return nil
+}
+
+// ValueFunc returns a Value made of a pair of functions, which are passed
+// and return the text of the value, unescaped
+func ValueFunc(serialize func() (string, error), deserialize func(string) error) Value {
+	return &valueFunc{serialize: serialize, deserialize: deserialize}
+}
+
+type valueFunc struct {
+	serialize   func() (string, error)
+	deserialize func(string) error
+}
+
+func (v *valueFunc) Serialize() ([]byte, error) {
+	text, err := v.serialize()
+	if err != nil {
+		return nil, err
+	}
+	return appendEscaped(nil, []byte(text)), nil
+}
+
+func (v *valueFunc) Deserialize(b []byte) error {
+	text, err := unescapeString(b)
+	if err != nil {
+		return err
+	}
+	return v.deserialize(string(text))
+}
+
+func parseInt(b []byte, bitSize int) (int64, error) {
+	text, err := unescapeString(b)
+	if err != nil {
+		return 0, err
+	}
+	if !jsonInteger.Match(text) {
+		return 0, valueError("integer", text, strconv.ErrSyntax)
+	}
+	n, err := strconv.ParseInt(string(text), 10, bitSize)
+	if err != nil {
+		return 0, valueError("integer", text, err)
+	}
+	return n, nil
+}
+
+// valueError is the error of a text that is not a valid value of kind
+func valueError(kind string, text []byte, err error) error {
+	if ne, ok := err.(*strconv.NumError); ok {
+		err = ne.Err
+	}
+	return fmt.Errorf("invalid %s \"%s\": %v", kind, text, err)
+}
@@ -0,0 +1,144 @@
+package jsontree
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValues(t *testing.T) {
+	tests := []struct {
+		value Value
+		in    string
+		out   string // if different from in
+		err   string
+	}{
+		{value: new(String), in: `a\"bé`},
+		{value: new(String), in: `\x`, err: `invalid escape '\x'`},
+		{value: new(Int), in: `-42`},
+		{value: new(Int), in: `1.5`, err: `invalid integer "1.5": invalid syntax`},
+		{value: new(Int), in: `+1`, err: `invalid integer "+1": invalid syntax`},
+		{value: new(Int), in: `007`, err: `invalid integer "007": invalid syntax`},
+		{value: new(Int64), in: `9223372036854775807`},
+		{value: new(Int64), in: `9223372036854775808`, err: `invalid integer "9223372036854775808": value out of range`},
+		{value: new(Uint), in: `18`},
+		{value: new(Uint), in: `-1`, err: `invalid unsigned integer "-1": invalid syntax`},
+		{value: new(Float64), in: `1.5e+300`},
+		{value: new(Float64), in: `0.10`, out: `0.1`},
+		{value: new(Float64), in: `NaN`, err: `invalid number "NaN": invalid syntax`},
+		{value: new(Float64), in: `1e999`, err: `invalid number "1e999": value out of range`},
+		{value: new(Bool), in: `true`},
+		{value: new(Bool), in: `1`, err: `invalid boolean "1": expected true or false`},
+		{value: new(Duration), in: `1h30m0s`},
+		{value: new(Duration), in: `90m`, out: `1h30m0s`},
+		{value: new(Duration), in: `90`, err: `invalid duration "90": expected a number and a unit, like 1h30m`},
+		{value: new(Time), in: `2024-03-05T14:07:09.5+01:00`},
+		{value: new(Time), in: `2024-03-05`, err: `invalid RFC 3339 time "2024-03-05": parsing time "2024-03-05" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "" as "T"`},
+		{value: new(Bytes), in: `aGVsbG8=`},
+		{value: new(Bytes), in: `aGVsbG8`, err: `invalid base64 "aGVsbG8": illegal base64 data at input byte 4`},
+		{value: new(URL), in: `https://example.com/a?b=c`},
+		{value: new(URL), in: `mailto:a@example.com`},
+		{value: new(URL), in: `/relative`, err: `invalid URL "/relative": expected an absolute URL`},
+		{value: new(URL), in: `http://[::1`, err: `invalid URL "http://[::1": missing ']' in host`},
+		{value: new(URL), in: ``},
+		{value: new(IP), in: `192.0.2.1`},
+		{value: new(IP), in: ``},
+		{value: new(IP), in: `2001:DB8::1`, out: `2001:db8::1`},
+		{value: new(IP), in: `192.0.2`, err: `invalid IP address "192.0.2": invalid syntax`},
+	}
+	for _, test := range tests {
+		name := fmt.Sprintf("%T %s", test.value, test.in)
+		err := test.value.Deserialize([]byte(test.in))
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: Wrong error\nWant %s\nGot  %v", name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Deserialize() error: %v", name, err)
+			continue
+		}
+		out := test.out
+		if out == "" {
+			out = test.in
+		}
+		if got, err := test.value.Serialize(); err != nil || string(got) != out {
+			t.Errorf("%s: Serialize() = %s, %v, want %s", name, got, err, out)
+		}
+	}
+}
+
+func TestValuesUnchangedOnError(t *testing.T) {
+	v := Int(7)
+	if err := v.Deserialize([]byte("x")); err == nil || v != 7 {
+		t.Errorf("Deserialize() = %v, value %d", err, v)
+	}
+	d := Duration(time.Second)
+	if err := d.Deserialize([]byte("")); err == nil || d != Duration(time.Second) {
+		t.Errorf("Deserialize() = %v, value %v", err, d)
+	}
+	f := Float64(0)
+	f = Float64(f / f)
+	if _, err := f.Serialize(); err == nil {
+		t.Errorf("Serialize() of NaN returned no error")
+	}
+}
+
+func TestValuesZero(t *testing.T) {
+	// Zero values round-trip through a document
+	var u URL
+	var ip IP
+	node := &testNode{key: key("root"), nodes: []*testNode{
+		{key: key("url"), value: &testValue{}},
+		{key: key("ip"), value: &testValue{}},
+	}}
+	for i, v := range []Value{&u, &ip} {
+		raw, err := v.Serialize()
+		if err != nil {
+			t.Fatalf("%T: Serialize() error: %v", v, err)
+		}
+		node.nodes[i].value.b = raw
+	}
+	var buf bytes.Buffer
+	if err := SerializeNode(node, &buf); err != nil {
+		t.Fatalf("SerializeNode() error: %v", err)
+	}
+	back := &testNode{}
+	if err := DeserializeNode(back, &buf); err != nil {
+		t.Fatalf("DeserializeNode() error: %v", err)
+	}
+	u2, ip2 := URL{Scheme: "x"}, IP{1}
+	for i, v := range []Value{&u2, &ip2} {
+		if err := v.Deserialize(back.nodes[i].value.b); err != nil {
+			t.Errorf("%T: Deserialize() error: %v", v, err)
+		}
+	}
+	if u2 != (URL{}) || ip2 != nil {
+		t.Errorf("Zero values did not round-trip: %v, %v", u2, ip2)
+	}
+}
+
+func TestValueFunc(t *testing.T) {
+	var level string
+	v := ValueFunc(func() (string, error) {
+		return strings.ToLower(level), nil
+	}, func(text string) error {
+		if text != "debug" && text != "info" {
+			return fmt.Errorf("unknown level \"%s\"", text)
+		}
+		level = strings.ToUpper(text)
+		return nil
+	})
+	if err := v.Deserialize([]byte(`info`)); err != nil || level != "INFO" {
+		t.Errorf("Deserialize() = %v, level %s", err, level)
+	}
+	if got, err := v.Serialize(); err != nil || string(got) != "info" {
+		t.Errorf("Serialize() = %s, %v", got, err)
+	}
+	if err := v.Deserialize([]byte(`\"x`)); err == nil || err.Error() != `unknown level ""x"` {
+		t.Errorf("Wrong error %v", err)
+	}
+}
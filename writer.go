@@ -14,6 +14,7 @@ type Writer struct {
 	hasWrittenNode   bool
 	hasWrittenParent bool
 	closed           bool
+	opts             EncodeOptions
 }
 
 func NewWriter(w io.Writer) *Writer {
@@ -36,6 +37,9 @@ func (writer *Writer) WriteParent(key []byte) error {
 	if writer.hasWrittenParent {
 		return errors.New("WriteParent() has already been called")
 	}
+	if writer.opts.MultipleRoots {
+		return errors.New("WriteParent() can not be used with MultipleRoots")
+	}
 	if _, err := writer.w.Write(jsonBytes[:2]); err != nil { // write {"
 		return err
 	}
@@ -53,6 +57,38 @@ func (writer *Writer) WriteNode(node Node) error {
 	if writer.closed {
 		return errors.New("the writer is closed")
 	}
+	if node == nil {
+		return fmt.Errorf("node is nil")
+	}
+	if writer.opts.Schema != nil {
+		if err := writer.opts.Schema.Validate(node); err != nil {
+			return err
+		}
+	}
+	if writer.opts.MultipleRoots {
+		if err := checkMultipleRoots(node); err != nil {
+			return err
+		}
+		// The children of node are written as top level keys, in the order
+		// of the options. Nodes of separate calls are written in call order.
+		nodes, err := writer.opts.order(node.Nodes())
+		if err != nil {
+			return err
+		}
+		for _, child := range nodes {
+			if child == nil {
+				return fmt.Errorf("invalid node: node.Nodes() contained nil")
+			}
+			if err := writer.writeNode(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return writer.writeNode(node)
+}
+
+func (writer *Writer) writeNode(node Node) error {
 	w := writer.w
 	if !writer.hasWrittenNode {
 		if err := w.WriteByte('{'); err != nil {
@@ -64,17 +100,23 @@ func (writer *Writer) WriteNode(node Node) error {
 			return err
 		}
 	}
-	return serializeNode(node, w)
+	return serializeNode(node, w, &writer.opts)
 }
 
 func (writer *Writer) Close() error {
 	if writer.closed {
 		return nil
 	}
+	w := writer.w
 	if !writer.hasWrittenNode {
-		return fmt.Errorf("must write atleast one node before closing")
+		if !writer.opts.MultipleRoots {
+			return fmt.Errorf("must write atleast one node before closing")
+		}
+		// An empty top level object
+		if err := w.WriteByte('{'); err != nil {
+			return err
+		}
 	}
-	w := writer.w
 	if err := w.WriteByte('}'); err != nil {
 		return err
 	}
@@ -66,6 +66,17 @@ func TestWriterWriteParent(t *testing.T) {
 			t.Errorf("Calling WriteParent() after calling WriteNode() ")
 		}
 	}
+	// Calling WriteParent with MultipleRoots returns error
+	{
+		var buf bytes.Buffer
+		w := EncodeOptions{MultipleRoots: true}.NewWriter(&buf)
+		want := fmt.Errorf("WriteParent() can not be used with MultipleRoots")
+		if err := w.WriteParent(parentKey); !errEqual(want, err) {
+			t.Errorf("Calling WriteParent() with MultipleRoots return wrong error.\nWant %v\nGot  %v", want, err)
+		} else if buf.Len() != 0 {
+			t.Errorf("Calling WriteParent() with MultipleRoots wrote to the writer")
+		}
+	}
 	// Calling WriteParent after Close() returns error
 	{
 		var buf bytes.Buffer
@@ -96,6 +107,18 @@ func TestWriterWriteNode(t *testing.T) {
 			t.Errorf("Calling WriteNode() after calling Close() wrote to the writer")
 		}
 	}
+	// A node with a value instead of children with MultipleRoots returns error
+	{
+		var buf bytes.Buffer
+		w := EncodeOptions{MultipleRoots: true}.NewWriter(&buf)
+		node := &testNode{key: key("doc"), value: val("hello")}
+		want := fmt.Errorf("invalid node: MultipleRoots requires children, not a value")
+		if err := w.WriteNode(node); !errEqual(want, err) {
+			t.Errorf("Calling WriteNode() with a value return wrong error.\nWant %v\nGot  %v", want, err)
+		} else if buf.Len() > 0 {
+			t.Errorf("Calling WriteNode() with a value wrote to the writer")
+		}
+	}
 }
 
 func TestWriterClose(t *testing.T) {
@@ -104,9 +127,15 @@ func TestWriterClose(t *testing.T) {
 		node   bool
 		parent bool
 		closed bool
+		multi  bool
 		want   string
 		err    error
 	}{
+		{
+			name:  "No nodes have been written with MultipleRoots",
+			multi: true,
+			want:  "{}",
+		},
 		{
 			name:   "Closing closed writer does nothing",
 			closed: true,
@@ -139,6 +168,7 @@ func TestWriterClose(t *testing.T) {
 		var buf bytes.Buffer
 		w := NewWriter(&buf)
 		w.closed, w.hasWrittenNode, w.hasWrittenParent = test.closed, test.node, test.parent
+		w.opts.MultipleRoots = test.multi
 		if err := w.Close(); !errEqual(test.err, err) {
 			t.Errorf("%s: Close() returned wrong error\nWant %v\nGot  %v", test.name, test.err, err)
 		}
@@ -0,0 +1,369 @@
+package jsontree
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// XLIFF files hold the leaves of a source tree and of its translation, a
+// target tree. The id of a unit is the path of its leaf below the root,
+// joined with dots, as the roots of the two trees usually have different
+// keys: their locales. XLIFF 2.0 ids must be XML name tokens, so there the
+// characters that can not be in one are written as _xHHHH_, the hexadecimal
+// code point between "_x" and "_", as is usual for XML names. So is the '_'
+// of a "_x" in the path.
+
+type XLIFFVersion int
+
+const (
+	XLIFF12 XLIFFVersion = iota
+	XLIFF20
+)
+
+// XLIFFOptions configures the XLIFF files written and read. SourceLanguage is
+// required for writing. File is the name of the file element, "messages" if
+// empty.
+type XLIFFOptions struct {
+	Version        XLIFFVersion
+	SourceLanguage string
+	TargetLanguage string
+	File           string
+}
+
+// xliffUnit is a leaf of the source tree
+type xliffUnit struct {
+	id   string
+	path [][]byte
+	node Node
+}
+
+// xliffUnits returns the units of the leaves of source, in order
+func xliffUnits(source Node) ([]xliffUnit, error) {
+	var units []xliffUnit
+	seen := make(map[string][][]byte)
+	var walk func(node Node, path [][]byte, ids []string) error
+	walk = func(node Node, path [][]byte, ids []string) error {
+		for _, child := range node.Nodes() {
+			if child == nil {
+				return fmt.Errorf("invalid node: node.Nodes() contained nil")
+			}
+			key, err := unescapeString(child.Key())
+			if err != nil {
+				return err
+			}
+			path := append(path[:len(path):len(path)], child.Key())
+			ids := append(ids[:len(ids):len(ids)], string(key))
+			if len(child.Nodes()) > 0 {
+				if err := walk(child, path, ids); err != nil {
+					return err
+				}
+				continue
+			}
+			id := strings.Join(ids, ".")
+			if other, ok := seen[id]; ok {
+				return fmt.Errorf("keys \"%s\" and \"%s\" have the same XLIFF id", bytes.Join(other, []byte{'.'}), bytes.Join(path, []byte{'.'}))
+			}
+			seen[id] = path
+			units = append(units, xliffUnit{id: id, path: path, node: child})
+		}
+		return nil
+	}
+	if source == nil {
+		return nil, fmt.Errorf("node is nil")
+	}
+	return units, walk(source, nil, nil)
+}
+
+// SerializeXLIFF writes the leaves of source and their translations in
+// target as an XLIFF file. Leaves missing from target have no target element.
+// target may be nil, for a file to be translated.
+func (opts XLIFFOptions) SerializeXLIFF(source, target Node, w io.Writer) error {
+	if opts.SourceLanguage == "" {
+		return fmt.Errorf("XLIFF requires a source language")
+	}
+	units, err := xliffUnits(source)
+	if err != nil {
+		return err
+	}
+	file := opts.File
+	if file == "" {
+		file = "messages"
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	if opts.Version == XLIFF20 {
+		fmt.Fprintf(bw, "<xliff version=\"2.0\" xmlns=\"urn:oasis:names:tc:xliff:document:2.0\" srcLang=\"%s\"", xmlEscape(opts.SourceLanguage))
+		if opts.TargetLanguage != "" {
+			fmt.Fprintf(bw, " trgLang=\"%s\"", xmlEscape(opts.TargetLanguage))
+		}
+		fmt.Fprintf(bw, ">\n  <file id=\"%s\">\n", xmlEscape(file))
+	} else {
+		fmt.Fprintf(bw, "<xliff version=\"1.2\" xmlns=\"urn:oasis:names:tc:xliff:document:1.2\">\n  <file original=\"%s\" source-language=\"%s\"", xmlEscape(file), xmlEscape(opts.SourceLanguage))
+		if opts.TargetLanguage != "" {
+			fmt.Fprintf(bw, " target-language=\"%s\"", xmlEscape(opts.TargetLanguage))
+		}
+		bw.WriteString(" datatype=\"plaintext\">\n    <body>\n")
+	}
+	for _, unit := range units {
+		sourceText, err := leafText(unit.node)
+		if err != nil {
+			return err
+		}
+		var targetText *string
+		if target != nil && len(unit.path) > 0 {
+			if n := getNode(target, unit.path...); n != nil && len(n.Nodes()) == 0 {
+				text, err := leafText(n)
+				if err != nil {
+					return err
+				}
+				targetText = &text
+			}
+		}
+		if opts.Version == XLIFF20 {
+			if unit.id == "" {
+				return fmt.Errorf("the empty key can not be an XLIFF 2.0 unit id")
+			}
+			fmt.Fprintf(bw, "    <unit id=\"%s\">\n      <segment>\n        <source>%s</source>\n", encodeNmtoken(unit.id), xmlEscape(sourceText))
+			if targetText != nil {
+				fmt.Fprintf(bw, "        <target>%s</target>\n", xmlEscape(*targetText))
+			}
+			bw.WriteString("      </segment>\n    </unit>\n")
+		} else {
+			fmt.Fprintf(bw, "      <trans-unit id=\"%s\">\n        <source>%s</source>\n", xmlEscape(unit.id), xmlEscape(sourceText))
+			if targetText != nil {
+				fmt.Fprintf(bw, "        <target>%s</target>\n", xmlEscape(*targetText))
+			}
+			bw.WriteString("      </trans-unit>\n")
+		}
+	}
+	if opts.Version == XLIFF20 {
+		bw.WriteString("  </file>\n</xliff>\n")
+	} else {
+		bw.WriteString("    </body>\n  </file>\n</xliff>\n")
+	}
+	return bw.Flush()
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// xliffText is the text of a source or target element. Inline elements
+// are not supported.
+type xliffText struct {
+	Text     string `xml:",chardata"`
+	Elements []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+type xliffSegment struct {
+	Source xliffText  `xml:"source"`
+	Target *xliffText `xml:"target"`
+}
+
+type xliffTransUnit struct {
+	ID string `xml:"id,attr"`
+	xliffSegment
+}
+
+type xliffUnit20 struct {
+	ID       string         `xml:"id,attr"`
+	Segments []xliffSegment `xml:"segment"`
+}
+
+// DeserializeXLIFF reads the targets of an XLIFF file of either version into
+// target. The unit ids are resolved against the leaves of source: units
+// without a leaf in source are reported in a MultiError, after the other
+// units have been read. Units without a target are skipped. If target has no
+// key, it is set to the target language of the file.
+func (opts XLIFFOptions) DeserializeXLIFF(target, source Node, r io.Reader) error {
+	if target == nil {
+		return fmt.Errorf("node is nil")
+	}
+	units, err := xliffUnits(source)
+	if err != nil {
+		return err
+	}
+	paths := make(map[string][][]byte, len(units))
+	for _, unit := range units {
+		paths[unit.id] = unit.path
+	}
+	d := xml.NewDecoder(r)
+	var errs MultiError
+	for {
+		line, column := d.InputPos()
+		pos := Position{Offset: int(d.InputOffset()), Line: line, Column: column}
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var id, text string
+		var translated bool
+		switch start.Name.Local {
+		case "xliff":
+			if version := xmlAttr(start, "version"); version != "1.2" && version != "2.0" {
+				return &SyntaxError{Pos: pos, Err: fmt.Errorf("unsupported XLIFF version \"%s\"", version)}
+			}
+			if err := opts.checkLanguage(target, xmlAttr(start, "trgLang")); err != nil {
+				return &SyntaxError{Pos: pos, Err: err}
+			}
+			continue
+		case "file":
+			if err := opts.checkLanguage(target, xmlAttr(start, "target-language")); err != nil {
+				return &SyntaxError{Pos: pos, Err: err}
+			}
+			continue
+		case "trans-unit":
+			var unit xliffTransUnit
+			if err := d.DecodeElement(&unit, &start); err != nil {
+				return err
+			}
+			id = unit.ID
+			if unit.Target != nil {
+				if len(unit.Target.Elements) > 0 {
+					return &SyntaxError{Pos: pos, Err: fmt.Errorf("unit \"%s\": inline elements are not supported", id)}
+				}
+				text, translated = unit.Target.Text, true
+			}
+		case "unit":
+			var unit xliffUnit20
+			if err := d.DecodeElement(&unit, &start); err != nil {
+				return err
+			}
+			id = decodeNmtoken(unit.ID)
+			for _, segment := range unit.Segments {
+				if segment.Target != nil {
+					if len(segment.Target.Elements) > 0 {
+						return &SyntaxError{Pos: pos, Err: fmt.Errorf("unit \"%s\": inline elements are not supported", id)}
+					}
+					text += segment.Target.Text
+					translated = true
+				}
+			}
+		default:
+			continue
+		}
+		path, ok := paths[id]
+		if !ok {
+			errs = append(errs, &SyntaxError{Pos: pos, Err: fmt.Errorf("unit \"%s\" has no key in the source tree", id)})
+			continue
+		}
+		if !translated {
+			continue
+		}
+		if err := getOrAddNode(target, path...).Value().Deserialize(appendEscaped(nil, []byte(text))); err != nil {
+			return err
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkLanguage checks the target language of the file, and sets the key of
+// target to it if it has none
+func (opts XLIFFOptions) checkLanguage(target Node, lang string) error {
+	if lang == "" {
+		return nil
+	}
+	if opts.TargetLanguage != "" && !strings.EqualFold(lang, opts.TargetLanguage) {
+		return fmt.Errorf("the target language is \"%s\", not \"%s\"", lang, opts.TargetLanguage)
+	}
+	if target.Key() == nil {
+		target.SetKey(appendEscaped(nil, []byte(lang)))
+	}
+	return nil
+}
+
+// encodeNmtoken writes the characters of id that can not be in an XML name
+// token as _xHHHH_, or _xHHHHHHHH_ beyond the Basic Multilingual Plane
+func encodeNmtoken(id string) string {
+	var b strings.Builder
+	for i, r := range id {
+		if isXMLNameChar(r) && !(r == '_' && strings.HasPrefix(id[i+1:], "x")) {
+			b.WriteRune(r)
+		} else if r > 0xffff {
+			fmt.Fprintf(&b, "_x%08X_", r)
+		} else {
+			fmt.Fprintf(&b, "_x%04X_", r)
+		}
+	}
+	return b.String()
+}
+
+func decodeNmtoken(id string) string {
+	if !strings.Contains(id, "_x") {
+		return id
+	}
+	var b strings.Builder
+	for i := 0; i < len(id); i++ {
+		if r, n := nmtokenEscape(id[i:]); n > 0 {
+			b.WriteRune(r)
+			i += n - 1
+			continue
+		}
+		b.WriteByte(id[i])
+	}
+	return b.String()
+}
+
+// nmtokenEscape returns the character escaped at the start of s, and the
+// length of the escape, 0 if there is none
+func nmtokenEscape(s string) (rune, int) {
+	if !strings.HasPrefix(s, "_x") {
+		return 0, 0
+	}
+	for _, n := range []int{4, 8} {
+		if len(s) <= 2+n || s[2+n] != '_' {
+			continue
+		}
+		if r, err := strconv.ParseUint(s[2:2+n], 16, 32); err == nil && utf8.ValidRune(rune(r)) {
+			return rune(r), 3 + n
+		}
+	}
+	return 0, 0
+}
+
+// isXMLNameChar reports whether r can be in an XML name (XML 1.0, NameChar)
+func isXMLNameChar(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	case r == ':' || r == '_' || r == '-' || r == '.' || r == 0xb7:
+		return true
+	case r >= 0xc0 && r <= 0xd6, r >= 0xd8 && r <= 0xf6, r >= 0xf8 && r <= 0x37d:
+		return true
+	case r >= 0x37f && r <= 0x1fff, r >= 0x200c && r <= 0x200d, r >= 0x203f && r <= 0x2040:
+		return true
+	case r >= 0x2070 && r <= 0x218f, r >= 0x2c00 && r <= 0x2fef, r >= 0x3001 && r <= 0xd7ff:
+		return true
+	case r >= 0xf900 && r <= 0xfdcf, r >= 0xfdf0 && r <= 0xfffd, r >= 0x10000 && r <= 0xeffff:
+		return true
+	}
+	return false
+}
+
+func xmlAttr(start xml.StartElement, name string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
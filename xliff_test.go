@@ -0,0 +1,172 @@
+package jsontree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestXLIFF(t *testing.T) {
+	source, target := &testNode{}, &testNode{}
+	if err := DeserializeNode(source, strings.NewReader(`{"en":{"greeting":"Hello <b>&</b>","menu":{"open":"Open","close":"Close"}}}`)); err != nil {
+		t.Fatalf("DeserializeNode() error: %v", err)
+	}
+	if err := DeserializeNode(target, strings.NewReader(`{"de":{"greeting":"Hallo","menu":{"open":"Öffnen"},"extra":"x"}}`)); err != nil {
+		t.Fatalf("DeserializeNode() error: %v", err)
+	}
+	tests := []struct {
+		version XLIFFVersion
+		want    string
+	}{
+		{
+			version: XLIFF12,
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">
+  <file original="messages" source-language="en" target-language="de" datatype="plaintext">
+    <body>
+      <trans-unit id="greeting">
+        <source>Hello &lt;b&gt;&amp;&lt;/b&gt;</source>
+        <target>Hallo</target>
+      </trans-unit>
+      <trans-unit id="menu.open">
+        <source>Open</source>
+        <target>Öffnen</target>
+      </trans-unit>
+      <trans-unit id="menu.close">
+        <source>Close</source>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>
+`,
+		},
+		{
+			version: XLIFF20,
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="2.0" xmlns="urn:oasis:names:tc:xliff:document:2.0" srcLang="en" trgLang="de">
+  <file id="messages">
+    <unit id="greeting">
+      <segment>
+        <source>Hello &lt;b&gt;&amp;&lt;/b&gt;</source>
+        <target>Hallo</target>
+      </segment>
+    </unit>
+    <unit id="menu.open">
+      <segment>
+        <source>Open</source>
+        <target>Öffnen</target>
+      </segment>
+    </unit>
+    <unit id="menu.close">
+      <segment>
+        <source>Close</source>
+      </segment>
+    </unit>
+  </file>
+</xliff>
+`,
+		},
+	}
+	for _, test := range tests {
+		opts := XLIFFOptions{Version: test.version, SourceLanguage: "en", TargetLanguage: "de"}
+		var buf bytes.Buffer
+		if err := opts.SerializeXLIFF(source, target, &buf); err != nil {
+			t.Fatalf("%d: SerializeXLIFF() error: %v", test.version, err)
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("%d: Wrong XLIFF\nWant %s\nGot  %s", test.version, test.want, got)
+		}
+		// Importing takes the key of the target from the file
+		imported := &testNode{}
+		if err := (XLIFFOptions{}).DeserializeXLIFF(imported, source, &buf); err != nil {
+			t.Fatalf("%d: DeserializeXLIFF() error: %v", test.version, err)
+		}
+		want := `{"de":{"greeting":"Hallo","menu":{"open":"Öffnen"}}}`
+		if got := nodeString(imported); got != want {
+			t.Errorf("%d: Wrong tree imported\nWant %s\nGot  %s", test.version, want, got)
+		}
+	}
+}
+
+func TestDeserializeXLIFFErrors(t *testing.T) {
+	source := &testNode{key: key("en"), nodes: []*testNode{{key: key("a"), value: val("A")}}}
+	tests := []struct {
+		name string
+		opts XLIFFOptions
+		in   string
+		want string
+		err  string
+	}{
+		{
+			name: "unknown units",
+			in: `<xliff version="1.2"><file target-language="de"><body>
+<trans-unit id="b"><source>B</source><target>BB</target></trans-unit>
+<trans-unit id="a"><source>A</source><target>AA</target></trans-unit>
+<trans-unit id="c"><source>C</source></trans-unit>
+</body></file></xliff>`,
+			want: `{"de":{"a":"AA"}}`,
+			err:  "2:1: unit \"b\" has no key in the source tree (at \"\")\n4:1: unit \"c\" has no key in the source tree (at \"\")",
+		},
+		{
+			name: "wrong language",
+			opts: XLIFFOptions{TargetLanguage: "fr"},
+			in:   `<xliff version="2.0" trgLang="de"></xliff>`,
+			err:  `1:1: the target language is "de", not "fr" (at "")`,
+		},
+		{
+			name: "inline elements",
+			in:   `<xliff version="2.0"><file><unit id="a"><segment><source>A</source><target>A<ph id="1"/></target></segment></unit></file></xliff>`,
+			err:  `1:28: unit "a": inline elements are not supported (at "")`,
+		},
+		{
+			name: "version",
+			in:   `<xliff version="1.0"></xliff>`,
+			err:  `1:1: unsupported XLIFF version "1.0" (at "")`,
+		},
+	}
+	for _, test := range tests {
+		target := &testNode{}
+		err := test.opts.DeserializeXLIFF(target, source, strings.NewReader(test.in))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%s: Wrong error\nWant %s\nGot  %v", test.name, test.err, err)
+		}
+		if test.want != "" {
+			if got := nodeString(target); got != test.want {
+				t.Errorf("%s: Wrong tree\nWant %s\nGot  %s", test.name, test.want, got)
+			}
+		}
+	}
+}
+
+func TestXLIFF20IDs(t *testing.T) {
+	source := &testNode{}
+	if err := DeserializeNode(source, strings.NewReader(`{"en":{"a b":"1","x/y":{"_x0020_":"2"},"café":"3","😀":"4","\udb80\udc00":"5"}}`)); err != nil {
+		t.Fatalf("DeserializeNode() error: %v", err)
+	}
+	var buf bytes.Buffer
+	for _, version := range []XLIFFVersion{XLIFF12, XLIFF20} {
+		if err := (XLIFFOptions{Version: version}).SerializeXLIFF(source, nil, &buf); err == nil || err.Error() != "XLIFF requires a source language" {
+			t.Errorf("%d: Wrong error %v", version, err)
+		}
+	}
+	if err := (XLIFFOptions{Version: XLIFF20, SourceLanguage: "en", TargetLanguage: "de"}).SerializeXLIFF(source, source, &buf); err != nil {
+		t.Fatalf("SerializeXLIFF() error: %v", err)
+	}
+	var ids []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if i := strings.Index(line, `<unit id="`); i >= 0 {
+			ids = append(ids, strings.TrimSuffix(line[i+len(`<unit id="`):], `">`))
+		}
+	}
+	want := []string{"a_x0020_b", "x_x002F_y._x005F_x0020_", "café", "😀", "_x000F0000_"}
+	if strings.Join(ids, " ") != strings.Join(want, " ") {
+		t.Errorf("Wrong ids\nWant %v\nGot  %v", want, ids)
+	}
+	imported := &testNode{}
+	if err := (XLIFFOptions{}).DeserializeXLIFF(imported, source, &buf); err != nil {
+		t.Fatalf("DeserializeXLIFF() error: %v", err)
+	}
+	if got, want := nodeString(imported), `{"de":{"a b":"1","x/y":{"_x0020_":"2"},"café":"3","😀":"4","\udb80\udc00":"5"}}`; got != want {
+		t.Errorf("Wrong tree imported\nWant %s\nGot  %s", want, got)
+	}
+}
@@ -0,0 +1,694 @@
+package jsontree
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The YAML support covers what a Node tree can represent: nested block
+// mappings of string scalars. Plain, quoted and block scalars are read;
+// sequences, flow collections, anchors, aliases, tags and multiple
+// documents are not. Keys and values are converted between the plain text
+// of YAML and the escaped contents of JSON strings.
+
+func DeserializeYAML(node Node, r io.Reader) error {
+	return DecodeOptions{}.DeserializeYAML(node, r)
+}
+
+func SerializeYAML(node Node, w io.Writer) error {
+	return EncodeOptions{}.SerializeYAML(node, w)
+}
+
+// DeserializeYAML reads a YAML document into node. Like DeserializeNode, the
+// document must have a single top-level key unless opts.MultipleRoots is
+// set. MaxBytes is the only limit that applies. YAML requires the keys of a
+// mapping to be unique, so a repeated key is a *DuplicateKeyError whatever
+// opts.Duplicates is.
+func (opts DecodeOptions) DeserializeYAML(node Node, r io.Reader) error {
+	data, err := readAll(r, opts.MaxBytes)
+	if err != nil {
+		return err
+	}
+	y := &yamlReader{data: data, root: node, multi: opts.MultipleRoots}
+	return y.read()
+}
+
+// SerializeYAML writes node as a YAML document. Multi-line values are written
+// as literal block scalars.
+func (opts EncodeOptions) SerializeYAML(node Node, w io.Writer) error {
+	if node == nil {
+		return fmt.Errorf("node is nil")
+	}
+	y := &yamlWriter{w: bufio.NewWriter(w), opts: &opts}
+	var err error
+	if !opts.MultipleRoots {
+		err = y.writeNode(node, 0)
+	} else if nodes := node.Nodes(); len(nodes) > 0 {
+		err = y.writeNodes(nodes, 0)
+	} else {
+		_, err = y.w.WriteString("{}\n")
+	}
+	if err != nil {
+		return err
+	}
+	return y.w.Flush()
+}
+
+type yamlReader struct {
+	data    []byte
+	next    int // offset of the next line
+	offset  int // offset of the current line
+	line    int
+	root    Node
+	multi   bool
+	frames  []yamlFrame
+	hasRoot bool
+	empty   bool // the document is {}
+}
+
+// yamlFrame is a mapping being read
+type yamlFrame struct {
+	node      Node
+	key       []byte
+	indent    int // indentation of the keys of the mapping, -1 until known
+	keyIndent int // indentation of the key of the mapping
+	keys      map[string]Position
+}
+
+func (y *yamlReader) read() error {
+	y.frames = []yamlFrame{{node: y.root, indent: -1, keyIndent: -1}}
+	for {
+		line, ok := y.nextLine()
+		if !ok {
+			break
+		}
+		indent := countIndent(line)
+		rest := line[indent:]
+		if len(rest) == 0 || rest[0] == '#' {
+			continue
+		}
+		if rest[0] == '\t' {
+			return y.errorf(indent+1, "tabs can not be used for indentation")
+		}
+		if indent == 0 && isYAMLMarker(line, "---") {
+			if y.frames[0].indent >= 0 || y.empty {
+				return y.errorf(1, "multiple documents are not supported")
+			}
+			if len(trimYAMLComment(line[3:])) > 0 {
+				return y.errorf(5, "the document must be a mapping")
+			}
+			continue
+		}
+		if indent == 0 && isYAMLMarker(line, "...") {
+			break
+		}
+		if len(y.frames) == 1 && y.frames[0].indent < 0 && string(trimYAMLComment(rest)) == "{}" {
+			y.empty = true
+			continue
+		}
+		if y.empty {
+			return y.errorf(indent+1, "unexpected content after {}")
+		}
+		if err := y.readEntry(indent, rest); err != nil {
+			return err
+		}
+	}
+	for len(y.frames) > 1 {
+		if err := y.pop(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nextLine returns the next line of the document, without its line break
+func (y *yamlReader) nextLine() ([]byte, bool) {
+	line, ok := y.peekLine()
+	if ok {
+		y.offset = y.next
+		y.next += len(line)
+		if y.next < len(y.data) {
+			y.next++ // the line break
+		}
+		y.line++
+	}
+	return bytes.TrimSuffix(line, []byte{'\r'}), ok
+}
+
+func (y *yamlReader) peekLine() ([]byte, bool) {
+	if y.next >= len(y.data) {
+		return nil, false
+	}
+	line := y.data[y.next:]
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	return line, true
+}
+
+// readEntry reads a "key: value" line, indented by indent spaces
+func (y *yamlReader) readEntry(indent int, entry []byte) error {
+	for {
+		top := &y.frames[len(y.frames)-1]
+		if top.indent < 0 {
+			if indent > top.keyIndent {
+				top.indent = indent
+				break
+			}
+			// The key of the frame has no value
+			if err := y.pop(); err != nil {
+				return err
+			}
+			continue
+		}
+		if indent == top.indent {
+			break
+		}
+		if indent > top.indent || len(y.frames) == 1 {
+			return y.errorf(indent+1, "unexpected indentation")
+		}
+		if err := y.pop(); err != nil {
+			return err
+		}
+	}
+	if entry[0] == '-' && (len(entry) == 1 || entry[1] == ' ') {
+		return y.errorf(indent+1, "sequences are not supported")
+	}
+	key, value, err := y.readKey(entry, indent+1)
+	if err != nil {
+		return err
+	}
+	column := indent + 1 + len(entry) - len(value)
+	var child Node
+	if len(y.frames) == 1 && !y.multi {
+		if y.hasRoot {
+			return y.errorf(indent+1, "expected 1 root node")
+		}
+		y.root.SetKey(key)
+		child, y.hasRoot = y.root, true
+	} else {
+		if err := y.checkDuplicate(key, indent+1); err != nil {
+			return err
+		}
+		child = y.frames[len(y.frames)-1].node.AddNode(key)
+	}
+	if len(value) == 0 || value[0] == '#' {
+		// The value is a mapping, or empty
+		y.frames = append(y.frames, yamlFrame{node: child, key: key, indent: -1, keyIndent: indent})
+		return nil
+	}
+	var text []byte
+	switch value[0] {
+	case '|', '>':
+		if text, err = y.readBlockScalar(value, indent, column); err != nil {
+			return err
+		}
+	case '"', '\'':
+		var rest []byte
+		if text, rest, err = y.readQuotedScalar(value, column); err != nil {
+			return err
+		}
+		if rest = trimYAMLComment(rest); len(rest) > 0 {
+			return y.errorf(column+len(value)-len(rest), "unexpected '%s' after quoted scalar", rest[:1])
+		}
+	case '&', '*', '!':
+		return y.errorf(column, "anchors, aliases and tags are not supported")
+	case '[', '{':
+		return y.errorf(column, "flow collections are not supported")
+	case '@', '`', '%':
+		return y.errorf(column, "'%c' can not start a plain scalar", value[0])
+	default:
+		text = trimYAMLComment(value)
+		if bytes.Contains(text, []byte(": ")) {
+			return y.errorf(column, "mapping values are not allowed here")
+		}
+	}
+	return y.setValue(child, text)
+}
+
+// readKey reads the key of entry, returning it escaped, and what follows the
+// ':' after it
+func (y *yamlReader) readKey(entry []byte, column int) (key, rest []byte, err error) {
+	var text []byte
+	switch entry[0] {
+	case '"', '\'':
+		if text, rest, err = y.readQuotedScalar(entry, column); err != nil {
+			return nil, nil, err
+		}
+		rest = bytes.TrimLeft(rest, " \t")
+		if len(rest) == 0 || rest[0] != ':' {
+			return nil, nil, y.errorf(column+len(entry)-len(rest), "expected ':'")
+		}
+		rest = rest[1:]
+	case '?':
+		return nil, nil, y.errorf(column, "complex keys are not supported")
+	default:
+		i := 0
+		for ; i < len(entry); i++ {
+			if entry[i] == ':' && (i+1 == len(entry) || entry[i+1] == ' ' || entry[i+1] == '\t') {
+				break
+			}
+			if entry[i] == '#' && (entry[i-1] == ' ' || entry[i-1] == '\t') {
+				i = len(entry) // a comment before the ':'
+				break
+			}
+		}
+		if i == len(entry) {
+			return nil, nil, y.errorf(column, "expected 'key: value'")
+		}
+		text, rest = bytes.TrimRight(entry[:i], " \t"), entry[i+1:]
+	}
+	return appendEscaped(nil, text), bytes.TrimLeft(rest, " \t"), nil
+}
+
+// readQuotedScalar reads the single or double quoted scalar at the start of
+// s, returning its text and what follows it. Quoted scalars must fit on a line.
+func (y *yamlReader) readQuotedScalar(s []byte, column int) (text, rest []byte, err error) {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case s[i] == quote && quote == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == quote:
+			if quote == '\'' {
+				return bytes.ReplaceAll(s[1:i], []byte("''"), []byte("'")), s[i+1:], nil
+			}
+			if text, err = unescapeYAML(s[1:i]); err != nil {
+				return nil, nil, y.errorf(column, "%v", err)
+			}
+			return text, s[i+1:], nil
+		}
+	}
+	return nil, nil, y.errorf(column, "unterminated quoted scalar")
+}
+
+// readBlockScalar reads a block scalar, whose header is at the start of
+// header, the value of a key indented by indent spaces
+func (y *yamlReader) readBlockScalar(header []byte, indent, column int) ([]byte, error) {
+	folded := header[0] == '>'
+	var chomp byte
+	contentIndent := -1
+	i := 1
+	for ; i < len(header); i++ {
+		if b := header[i]; (b == '-' || b == '+') && chomp == 0 {
+			chomp = b
+		} else if b >= '1' && b <= '9' && contentIndent < 0 {
+			contentIndent = indent + int(b-'0')
+		} else {
+			break
+		}
+	}
+	if rest := header[i:]; len(trimYAMLComment(rest)) > 0 || (len(rest) > 0 && rest[0] != ' ' && rest[0] != '\t') {
+		return nil, y.errorf(column, "invalid block scalar header '%s'", header)
+	}
+	var lines [][]byte
+	for {
+		line, ok := y.peekLine()
+		if !ok {
+			break
+		}
+		line = bytes.TrimSuffix(line, []byte{'\r'})
+		n := countIndent(line)
+		if n == len(line) {
+			// An empty line, or one of spaces only
+			if contentIndent >= 0 && n > contentIndent {
+				lines = append(lines, line[contentIndent:])
+			} else {
+				lines = append(lines, nil)
+			}
+			y.nextLine()
+			continue
+		}
+		if contentIndent < 0 {
+			if n <= indent {
+				break
+			}
+			contentIndent = n
+		}
+		if n < contentIndent {
+			break
+		}
+		lines = append(lines, line[contentIndent:])
+		y.nextLine()
+	}
+	return blockScalarText(lines, folded, chomp), nil
+}
+
+// blockScalarText returns the text of the lines of a block scalar
+func blockScalarText(lines [][]byte, folded bool, chomp byte) []byte {
+	end := len(lines)
+	for end > 0 && len(lines[end-1]) == 0 {
+		end--
+	}
+	body, trailing := lines[:end], len(lines)-end
+	moreIndented := func(line []byte) bool {
+		return len(line) > 0 && (line[0] == ' ' || line[0] == '\t')
+	}
+	var text []byte
+	for i := 0; i < len(body); {
+		line := body[i]
+		text = append(text, line...)
+		j := i + 1
+		for j < len(body) && len(body[j]) == 0 {
+			j++
+		}
+		if j == len(body) {
+			break
+		}
+		// Line breaks between lines of text are folded, unless one of the
+		// lines is more indented
+		breaks := j - i
+		if folded && len(line) > 0 && !moreIndented(line) && !moreIndented(body[j]) {
+			breaks--
+			if breaks == 0 {
+				text = append(text, ' ')
+			}
+		}
+		text = append(text, bytes.Repeat([]byte{'\n'}, breaks)...)
+		i = j
+	}
+	switch {
+	case chomp == '-':
+	case chomp == '+':
+		if len(body) > 0 {
+			trailing++
+		}
+		text = append(text, bytes.Repeat([]byte{'\n'}, trailing)...)
+	case len(body) > 0:
+		text = append(text, '\n')
+	}
+	return text
+}
+
+func (y *yamlReader) setValue(node Node, text []byte) error {
+	return node.Value().Deserialize(appendEscaped(nil, text))
+}
+
+// pop ends the mapping being read. A key without a value or a mapping has
+// an empty value.
+func (y *yamlReader) pop() error {
+	top := y.frames[len(y.frames)-1]
+	y.frames = y.frames[:len(y.frames)-1]
+	if top.indent < 0 {
+		return y.setValue(top.node, nil)
+	}
+	return nil
+}
+
+// checkDuplicate records the key of an entry of the current mapping at
+// column. YAML requires the keys of a mapping to be unique, so a key found
+// before is a *DuplicateKeyError.
+func (y *yamlReader) checkDuplicate(key []byte, column int) error {
+	top := &y.frames[len(y.frames)-1]
+	if top.keys == nil {
+		top.keys = make(map[string]Position)
+	}
+	pos := Position{Offset: y.offset + column - 1, Line: y.line, Column: column}
+	first, ok := top.keys[string(key)]
+	if !ok {
+		top.keys[string(key)] = pos
+		return nil
+	}
+	path := make([][]byte, 0, len(y.frames))
+	for _, frame := range y.frames[1:] {
+		path = append(path, frame.key)
+	}
+	return &DuplicateKeyError{Path: append(path, key), First: first, Second: pos}
+}
+
+func (y *yamlReader) errorf(column int, format string, args ...interface{}) error {
+	path := make([][]byte, 0, len(y.frames))
+	for _, frame := range y.frames[1:] {
+		path = append(path, frame.key)
+	}
+	return &SyntaxError{
+		Path: path,
+		Pos:  Position{Offset: y.offset + column - 1, Line: y.line, Column: column},
+		Err:  fmt.Errorf(format, args...),
+	}
+}
+
+func countIndent(line []byte) int {
+	n := 0
+	for n < len(line) && line[n] == ' ' {
+		n++
+	}
+	return n
+}
+
+func isYAMLMarker(line []byte, marker string) bool {
+	return bytes.HasPrefix(line, []byte(marker)) &&
+		(len(line) == len(marker) || line[len(marker)] == ' ' || line[len(marker)] == '\t')
+}
+
+// trimYAMLComment removes the comment at the end of s, and the spaces around s
+func trimYAMLComment(s []byte) []byte {
+	s = bytes.TrimLeft(s, " \t")
+	for i := range s {
+		if s[i] == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t') {
+			s = s[:i]
+			break
+		}
+	}
+	return bytes.TrimRight(s, " \t")
+}
+
+// unescapeYAML returns the text of the contents of a double quoted scalar
+func unescapeYAML(raw []byte) ([]byte, error) {
+	if bytes.IndexByte(raw, '\\') < 0 {
+		return raw, nil
+	}
+	text := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' {
+			text = append(text, raw[i])
+			continue
+		}
+		if i+1 == len(raw) {
+			return nil, fmt.Errorf("invalid escape at end of string")
+		}
+		i++
+		var r rune
+		switch esc := raw[i]; esc {
+		case '0':
+			r = 0
+		case 'a':
+			r = '\a'
+		case 'b':
+			r = '\b'
+		case 't', '\t':
+			r = '\t'
+		case 'n':
+			r = '\n'
+		case 'v':
+			r = '\v'
+		case 'f':
+			r = '\f'
+		case 'r':
+			r = '\r'
+		case 'e':
+			r = 0x1b
+		case ' ', '"', '/', '\\':
+			r = rune(esc)
+		case 'N':
+			r = 0x85
+		case '_':
+			r = 0xa0
+		case 'L':
+			r = 0x2028
+		case 'P':
+			r = 0x2029
+		case 'x', 'u', 'U':
+			n := map[byte]int{'x': 2, 'u': 4, 'U': 8}[esc]
+			if i+n >= len(raw) {
+				return nil, fmt.Errorf("invalid escape '\\%c'", esc)
+			}
+			v, err := strconv.ParseUint(string(raw[i+1:i+1+n]), 16, 32)
+			if err != nil || !utf8.ValidRune(rune(v)) {
+				return nil, fmt.Errorf("invalid escape '\\%s'", raw[i:i+1+n])
+			}
+			r = rune(v)
+			i += n
+		default:
+			return nil, fmt.Errorf("invalid escape '\\%c'", esc)
+		}
+		text = utf8.AppendRune(text, r)
+	}
+	return text, nil
+}
+
+type yamlWriter struct {
+	w    *bufio.Writer
+	opts *EncodeOptions
+}
+
+func (y *yamlWriter) writeNodes(nodes []Node, indent int) error {
+	nodes, err := y.opts.order(nodes)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if node == nil {
+			return fmt.Errorf("invalid node: node.Nodes() contained nil")
+		}
+		if err := y.writeNode(node, indent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (y *yamlWriter) writeNode(node Node, indent int) error {
+	key, err := unescapeString(node.Key())
+	if err != nil {
+		return err
+	}
+	y.writeIndent(indent)
+	y.writeScalar(key, indent == 0)
+	y.w.WriteByte(':')
+	if nodes := node.Nodes(); len(nodes) > 0 {
+		y.w.WriteByte('\n')
+		return y.writeNodes(nodes, indent+2)
+	}
+	value := node.Value()
+	if value == nil {
+		return fmt.Errorf("invalid node: len(node.Nodes()) == 0 and node.Value() == nil")
+	}
+	raw, err := value.Serialize()
+	if err != nil {
+		return err
+	}
+	text, err := unescapeString(raw)
+	if err != nil {
+		return err
+	}
+	y.w.WriteByte(' ')
+	if isYAMLBlock(text) {
+		y.writeBlock(text, indent+2)
+		return nil
+	}
+	y.writeScalar(text, false)
+	return y.w.WriteByte('\n')
+}
+
+func (y *yamlWriter) writeIndent(indent int) {
+	for i := 0; i < indent; i++ {
+		y.w.WriteByte(' ')
+	}
+}
+
+// writeScalar writes text as a plain scalar if it reads back as the same
+// string, and as a double quoted one otherwise
+func (y *yamlWriter) writeScalar(text []byte, topLevel bool) {
+	if isYAMLPlain(text) && !(topLevel && bytes.HasPrefix(text, []byte("..."))) {
+		y.w.Write(text)
+		return
+	}
+	y.w.Write(appendYAMLQuoted(nil, text))
+}
+
+// writeBlock writes text, which ends with a line break or has several lines,
+// as a literal block scalar
+func (y *yamlWriter) writeBlock(text []byte, indent int) {
+	body := bytes.TrimRight(text, "\n")
+	trailing := len(text) - len(body)
+	y.w.WriteByte('|')
+	if body[0] == ' ' {
+		// The indentation can not be detected from the first line
+		y.w.WriteByte('2')
+	}
+	switch {
+	case trailing == 0:
+		y.w.WriteByte('-')
+	case trailing > 1:
+		y.w.WriteByte('+')
+	}
+	y.w.WriteByte('\n')
+	for _, line := range bytes.Split(body, []byte{'\n'}) {
+		if len(line) > 0 {
+			y.writeIndent(indent)
+			y.w.Write(line)
+		}
+		y.w.WriteByte('\n')
+	}
+	for i := 1; i < trailing; i++ {
+		y.w.WriteByte('\n')
+	}
+}
+
+// isYAMLBlock reports whether text is written as a block scalar
+func isYAMLBlock(text []byte) bool {
+	if bytes.IndexByte(text, '\n') < 0 || len(bytes.Trim(text, "\n")) == 0 {
+		return false
+	}
+	for _, r := range string(text) {
+		if r != '\n' && r != '\t' && !isYAMLPrintable(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isYAMLPlain reports whether text can be written as a plain scalar
+func isYAMLPlain(text []byte) bool {
+	if len(text) == 0 || bytes.IndexByte([]byte("-?:,[]{}#&*!|>'\"%@` \t"), text[0]) >= 0 {
+		return false
+	}
+	if last := text[len(text)-1]; last == ' ' || last == '\t' || last == ':' {
+		return false
+	}
+	if bytes.Contains(text, []byte(": ")) || bytes.Contains(text, []byte(" #")) {
+		return false
+	}
+	for _, r := range string(text) {
+		if r == '\t' || !isYAMLPrintable(r) {
+			return false
+		}
+	}
+	// Strings that other YAML parsers resolve to other types
+	switch strings.ToLower(string(text)) {
+	case "null", "~", "true", "false", "yes", "no", "on", "off", "y", "n", ".inf", "+.inf", "-.inf", ".nan":
+		return false
+	}
+	if _, err := strconv.ParseFloat(string(text), 64); err == nil {
+		return false
+	}
+	if _, err := strconv.ParseInt(string(text), 0, 64); err == nil {
+		return false
+	}
+	return true
+}
+
+func isYAMLPrintable(r rune) bool {
+	return (r >= 0x20 && r <= 0x7e) || r == 0x85 || (r >= 0xa0 && r <= 0xd7ff) ||
+		(r >= 0xe000 && r <= 0xfffd && r != utf8.RuneError) || r >= 0x10000
+}
+
+// appendYAMLQuoted appends text to dst as a double quoted scalar
+func appendYAMLQuoted(dst, text []byte) []byte {
+	dst = append(dst, '"')
+	for _, r := range string(text) {
+		switch {
+		case r == '"' || r == '\\':
+			dst = append(dst, '\\', byte(r))
+		case r == '\n':
+			dst = append(dst, '\\', 'n')
+		case r == '\r':
+			dst = append(dst, '\\', 'r')
+		case r == '\t':
+			dst = append(dst, '\\', 't')
+		case !isYAMLPrintable(r):
+			dst = append(dst, fmt.Sprintf("\\u%04x", r)...)
+		default:
+			dst = utf8.AppendRune(dst, r)
+		}
+	}
+	return append(dst, '"')
+}
@@ -0,0 +1,222 @@
+package jsontree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDeserializeYAML(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		multi bool
+		want  string
+		err   string
+	}{
+		{
+			name: "nested mappings",
+			in:   "en:\n  greeting: Hello\n  menu:\n    open: Open file\n    close: Close\n  bye: Bye\n",
+			want: `{"en":{"greeting":"Hello","menu":{"open":"Open file","close":"Close"},"bye":"Bye"}}`,
+		},
+		{
+			name: "comments and document markers",
+			in:   "# header\n---\nen: # the locale\n\n  a: x # comment\n  b: y#not a comment\n...\nignored",
+			want: `{"en":{"a":"x","b":"y#not a comment"}}`,
+		},
+		{
+			name: "quoted scalars",
+			in:   "en:\n  \"a b\": \"say \\\"hi\\\"\\n\\t\\u00e9\\x41\\N\"\n  'c''d': 'it''s # here'\n  e: \"\"\n",
+			want: `{"en":{"a b":"say \"hi\"\n\té` + "A\u0085" + `","c'd":"it's # here","e":""}}`,
+		},
+		{
+			name: "literal block scalars",
+			in:   "en:\n  clip: |\n    line 1\n\n      indented\n  strip: |-\n    text\n  keep: |+\n    text\n\n  indicator: |2\n      spaced\n  last: x\n",
+			want: `{"en":{"clip":"line 1\n\n  indented\n","strip":"text","keep":"text\n\n","indicator":"  spaced\n","last":"x"}}`,
+		},
+		{
+			name: "folded block scalars",
+			in:   "en:\n  a: >\n    one\n    two\n\n    three\n      more\n    four\n  b: >-\n    x\n    y\n",
+			want: `{"en":{"a":"one two\nthree\n  more\nfour\n","b":"x y"}}`,
+		},
+		{
+			name: "empty values",
+			in:   "en:\n  a:\n  b: \"\"\n  c:\n",
+			want: `{"en":{"a":"","b":"","c":""}}`,
+		},
+		{
+			name: "CRLF line breaks",
+			in:   "en:\r\n  a: x\r\n",
+			want: `{"en":{"a":"x"}}`,
+		},
+		{
+			name:  "multiple roots",
+			in:    "a: x\nb:\n  c: y\n",
+			multi: true,
+			want:  `{"a":"x","b":{"c":"y"}}`,
+		},
+		{
+			name:  "empty mapping",
+			in:    "{}\n",
+			multi: true,
+		},
+		{
+			name: "several roots",
+			in:   "a: x\nb: y\n",
+			err:  `2:1: expected 1 root node (at "")`,
+		},
+		{
+			name: "sequence",
+			in:   "en:\n  - a\n",
+			err:  `2:3: sequences are not supported (at "en")`,
+		},
+		{
+			name: "flow mapping",
+			in:   "en: {a: b}\n",
+			err:  `1:5: flow collections are not supported (at "")`,
+		},
+		{
+			name: "alias",
+			in:   "en:\n  a: *x\n",
+			err:  `2:6: anchors, aliases and tags are not supported (at "en")`,
+		},
+		{
+			name: "bad indentation",
+			in:   "en:\n    a: x\n  b: y\n",
+			err:  `3:3: unexpected indentation (at "")`,
+		},
+		{
+			name: "missing colon",
+			in:   "en:\n  a\n",
+			err:  `2:3: expected 'key: value' (at "en")`,
+		},
+		{
+			name: "unterminated quote",
+			in:   "en: \"abc\n",
+			err:  `1:5: unterminated quoted scalar (at "")`,
+		},
+		{
+			name: "tab indentation",
+			in:   "en:\n\ta: x\n",
+			err:  `2:1: tabs can not be used for indentation (at "en")`,
+		},
+		{
+			name: "multiple documents",
+			in:   "a: x\n---\nb: y\n",
+			err:  `2:1: multiple documents are not supported (at "")`,
+		},
+		{
+			name: "duplicate key",
+			in:   "en:\n  a: x\n  b: y\n  a: z\n",
+			err:  `duplicate key "en.a" at 4:3, first defined at 2:3`,
+		},
+		{
+			name: "duplicate mapping key",
+			in:   "en:\n  a: x\n  a:\n    b: y\n",
+			err:  `duplicate key "en.a" at 3:3, first defined at 2:3`,
+		},
+		{
+			name:  "duplicate top level key",
+			in:    "a: x\nb:\n  c: y\na: z\n",
+			multi: true,
+			err:   `duplicate key "a" at 4:1, first defined at 1:1`,
+		},
+	}
+	for _, test := range tests {
+		node := &testNode{}
+		err := DecodeOptions{MultipleRoots: test.multi}.DeserializeYAML(node, strings.NewReader(test.in))
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: Wrong error\nWant %s\nGot  %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %v", test.name, err)
+			continue
+		}
+		var buf bytes.Buffer
+		if err := (EncodeOptions{MultipleRoots: test.multi}).SerializeNode(node, &buf); err != nil {
+			t.Errorf("%s: SerializeNode() error: %v", test.name, err)
+		} else if got := buf.String(); test.want != "" && got != test.want {
+			t.Errorf("%s: Wrong tree\nWant %s\nGot  %s", test.name, test.want, got)
+		}
+	}
+}
+
+func TestSerializeYAML(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  string
+		multi bool
+		want  string
+	}{
+		{
+			name: "nested",
+			json: `{"en":{"greeting":"Hello, world","menu":{"open":"Open"}}}`,
+			want: "en:\n  greeting: Hello, world\n  menu:\n    open: Open\n",
+		},
+		{
+			name: "quoted",
+			json: `{"en":{"a b":"","num":"12","t":"yes","c":"a: b","h":"#x","q":"\"x\"","s":" x","u":"\u0007"}}`,
+			want: "en:\n  a b: \"\"\n  num: \"12\"\n  t: \"yes\"\n  c: \"a: b\"\n  h: \"#x\"\n  q: \"\\\"x\\\"\"\n  s: \" x\"\n  u: \"\\u0007\"\n",
+		},
+		{
+			name: "block scalars",
+			json: `{"en":{"clip":"a\nb\n","strip":"a\nb","keep":"a\n\n","spaced":"  a\n","cr":"a\r\nb","nl":"\n"}}`,
+			want: "en:\n  clip: |\n    a\n    b\n  strip: |-\n    a\n    b\n  keep: |+\n    a\n\n  spaced: |2\n      a\n  cr: \"a\\r\\nb\"\n  nl: \"\\n\"\n",
+		},
+		{
+			name:  "multiple roots",
+			json:  `{"b":"1","a":{"c":"2"}}`,
+			multi: true,
+			want:  "b: \"1\"\na:\n  c: \"2\"\n",
+		},
+		{
+			name:  "empty",
+			json:  `{}`,
+			multi: true,
+			want:  "{}\n",
+		},
+	}
+	for _, test := range tests {
+		node := &testNode{}
+		if err := (DecodeOptions{MultipleRoots: test.multi}).DeserializeNode(node, strings.NewReader(test.json)); err != nil {
+			t.Fatalf("%s: DeserializeNode() error: %v", test.name, err)
+		}
+		var buf bytes.Buffer
+		if err := (EncodeOptions{MultipleRoots: test.multi}).SerializeYAML(node, &buf); err != nil {
+			t.Errorf("%s: SerializeYAML() error: %v", test.name, err)
+			continue
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("%s: Wrong YAML\nWant %q\nGot  %q", test.name, test.want, got)
+		}
+		// Reading the YAML back gives the same tree
+		back := &testNode{}
+		if err := (DecodeOptions{MultipleRoots: test.multi}).DeserializeYAML(back, &buf); err != nil {
+			t.Errorf("%s: DeserializeYAML() error: %v", test.name, err)
+			continue
+		}
+		var json bytes.Buffer
+		if err := (EncodeOptions{MultipleRoots: test.multi}).SerializeNode(back, &json); err != nil {
+			t.Errorf("%s: SerializeNode() error: %v", test.name, err)
+		} else if got := json.String(); got != test.json {
+			t.Errorf("%s: Round trip changed the tree\nWant %s\nGot  %s", test.name, test.json, got)
+		}
+	}
+}
+
+func TestSerializeYAMLOrder(t *testing.T) {
+	node := &testNode{key: key("en"), nodes: []*testNode{
+		{key: key("b"), value: val("2")},
+		{key: key("a"), value: val("1")},
+	}}
+	var buf bytes.Buffer
+	if err := (EncodeOptions{Less: ByKey}).SerializeYAML(node, &buf); err != nil {
+		t.Fatalf("SerializeYAML() error: %v", err)
+	}
+	if got, want := buf.String(), "en:\n  a: \"1\"\n  b: \"2\"\n"; got != want {
+		t.Errorf("Wrong YAML\nWant %q\nGot  %q", want, got)
+	}
+}